@@ -12,6 +12,16 @@ const (
 	DialTimeout    = 4 * time.Second
 )
 
+// Strategy yields the successive delays of one retry sequence.
+type Strategy interface {
+	Next() time.Duration
+}
+
+// Ensure Backoff satisfies Strategy at compile time.
+var _ Strategy = (*Backoff)(nil)
+
+// Backoff is a jittered exponential Strategy: each delay is drawn from
+// [current/2, current) and current doubles up to max.
 type Backoff struct {
 	current time.Duration
 	max     time.Duration
@@ -38,3 +48,10 @@ func (b *Backoff) Next() time.Duration {
 func (b *Backoff) Reset(initial time.Duration) {
 	b.current = initial
 }
+
+type constant time.Duration
+
+// Constant returns a Strategy that always waits d.
+func Constant(d time.Duration) Strategy { return constant(d) }
+
+func (c constant) Next() time.Duration { return time.Duration(c) }
@@ -42,3 +42,12 @@ func TestReset(t *testing.T) {
 		t.Errorf("After Reset cxpected current equal to initial value %d, got %d", InitialBackoff, b.current)
 	}
 }
+
+func TestConstant(t *testing.T) {
+	s := Constant(3 * time.Second)
+	for i := 0; i < 3; i++ {
+		if d := s.Next(); d != 3*time.Second {
+			t.Fatalf("Next() = %v; want 3s", d)
+		}
+	}
+}
@@ -10,6 +10,8 @@ const (
 	ErrCodeInvalidJSON          = "invalid_json"
 	ErrCodeUnsupportedMediaType = "unsupported_media_type"
 	ErrValidationFailed         = "validation_failed"
+	ErrCodeTimeout              = "timeout"
+	ErrCodeInternal             = "internal_error"
 )
 
 type APIError struct {
@@ -2,6 +2,7 @@ package srvx
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"io"
@@ -27,6 +28,11 @@ type ServerConfig struct {
 	ShutdownTimeout time.Duration
 	Logger          lg.ZLogger
 	EnvPortKey      string
+	// DevMode adds panic stack traces to the 500 responses of handlers
+	// that panic. Keep it off in production.
+	DevMode bool
+	// TLS, if set, serves HTTPS instead of plain HTTP.
+	TLS *TLSConfig
 }
 
 const (
@@ -113,32 +119,82 @@ func RunServer(handler http.Handler, config ServerConfig) error {
 	srvAddr := net.JoinHostPort(config.Addr, config.Port)
 	errorLog := lg.StdLoggerAt(logger, zapcore.ErrorLevel)
 
+	var tlsConfig *tls.Config
+	var reloader *certReloader
+	if config.TLS != nil {
+		var err error
+		if tlsConfig, reloader, err = config.TLS.build(logger); err != nil {
+			return err
+		}
+	}
+
 	server := &http.Server{
 		Addr:              srvAddr,
-		Handler:           handler,
+		Handler:           Recover(logger, RecoverConfig{Stack: config.DevMode})(handler),
 		ReadTimeout:       config.ReadTimeout,
 		WriteTimeout:      config.WriteTimeout,
 		IdleTimeout:       config.IdleTimeout,
 		ReadHeaderTimeout: defaultReadHeaderTimeout,
 		ErrorLog:          errorLog,
+		TLSConfig:         tlsConfig,
+		// handlers find the server logger with zlog.FromContext
+		BaseContext: func(net.Listener) context.Context {
+			return lg.Attach(context.Background(), logger)
+		},
 	}
 	// Channel to listen interrupt signals
 	sigc := make(chan os.Signal, 1)
 	signal.Notify(sigc, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 	defer signal.Stop(sigc)
 
-	serveErr := make(chan error, 1)
+	serveErr := make(chan error, 2)
 
-	go func() {
-		logger.Info("Server starting", lg.String("addr", srvAddr))
-		serveErr <- server.ListenAndServe()
-	}()
+	var redirect *http.Server
+	if tlsConfig == nil {
+		go func() {
+			logger.Info("Server starting", lg.String("addr", srvAddr))
+			serveErr <- server.ListenAndServe()
+		}()
+	} else {
+		if reloader != nil {
+			reloadInterval := config.TLS.ReloadInterval
+			if reloadInterval <= 0 {
+				reloadInterval = defaultCertReloadInterval
+			}
+			watchCtx, stopWatch := context.WithCancel(context.Background())
+			defer stopWatch()
+			go reloader.watch(watchCtx, reloadInterval)
+		}
+		go func() {
+			logger.Info("Server starting", lg.String("addr", srvAddr), lg.Bool("tls", true))
+			serveErr <- server.ListenAndServeTLS("", "")
+		}()
+		if config.TLS.RedirectPort != "" {
+			redirect = &http.Server{
+				Addr:              net.JoinHostPort(config.Addr, config.TLS.RedirectPort),
+				Handler:           redirectHandler(config.Port),
+				ReadTimeout:       config.ReadTimeout,
+				WriteTimeout:      config.WriteTimeout,
+				IdleTimeout:       config.IdleTimeout,
+				ReadHeaderTimeout: defaultReadHeaderTimeout,
+				ErrorLog:          errorLog,
+			}
+			go func() {
+				logger.Info("HTTPS redirect starting", lg.String("addr", redirect.Addr))
+				serveErr <- redirect.ListenAndServe()
+			}()
+		}
+	}
 
 	select {
 	case sig := <-sigc:
 		logger.Info("shutdown signal", lg.String("signal", sig.String()))
 	case err := <-serveErr:
 		if err != nil && err != http.ErrServerClosed {
+			if redirect != nil {
+				_ = server.Close()
+				_ = redirect.Close()
+			}
 			return err
 		}
 	}
@@ -146,6 +202,12 @@ func RunServer(handler http.Handler, config ServerConfig) error {
 	ctx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
 	defer cancel()
 
+	if redirect != nil {
+		if err := redirect.Shutdown(ctx); err != nil {
+			logger.Error("HTTPS redirect shutdown failed", lg.Any("error", err))
+		}
+	}
+
 	// Attempt gracefully shutdown the server
 	if err := server.Shutdown(ctx); err != nil {
 		logger.Error("Server shutdown failed", lg.Any("error", err))
@@ -153,13 +215,16 @@ func RunServer(handler http.Handler, config ServerConfig) error {
 	}
 
 	// drain serveErr
-	select {
-	case err := <-serveErr:
-		if err != nil && err != http.ErrServerClosed {
-			logger.Error("server listener error", lg.Any("error", err))
-			return err
+	for drained := false; !drained; {
+		select {
+		case err := <-serveErr:
+			if err != nil && err != http.ErrServerClosed {
+				logger.Error("server listener error", lg.Any("error", err))
+				return err
+			}
+		default:
+			drained = true
 		}
-	default:
 	}
 
 	logger.Info("Server stopped gracefully")
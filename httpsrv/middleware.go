@@ -0,0 +1,341 @@
+package srvx
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	lg "github.com/azargarov/go-utils/zlog"
+)
+
+// HeaderRequestID carries the request ID in requests and responses.
+const HeaderRequestID = "X-Request-ID"
+
+const maxRequestIDLen = 128
+
+type requestIDKey struct{}
+
+// RequestID takes the request ID from the X-Request-ID header, or generates
+// one if it is missing or malformed, stores it in the request context and
+// echoes it in the response header.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(HeaderRequestID)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(HeaderRequestID, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// GetRequestID returns the request ID stored by RequestID, or "".
+func GetRequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// validRequestID accepts short IDs of printable ASCII without spaces, so a
+// client cannot inject arbitrary text into logs and headers.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// RecoverConfig holds optional Recover settings.
+type RecoverConfig struct {
+	// Stack adds the panic value and stack trace to the error response's
+	// details. Enable it in development only: it leaks internals to clients.
+	Stack bool
+}
+
+// Recover turns a panicking handler into a 500 APIError of code
+// internal_error and logs the panic with its stack trace. A nil logger uses
+// the one attached to the request context. If the handler already started
+// the response, Recover panics with http.ErrAbortHandler after logging, so
+// net/http aborts the connection instead of ending a truncated response as
+// if it were complete. http.ErrAbortHandler itself is re-raised as is.
+func Recover(logger lg.ZLogger, config ...RecoverConfig) Middleware {
+	var cfg RecoverConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := wrapWriter(w)
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				if err, ok := v.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					panic(v)
+				}
+				stack := string(debug.Stack())
+				requestLogger(logger, r).Error("Handler panicked",
+					lg.Any("panic", v),
+					lg.String("method", r.Method),
+					lg.String("path", r.URL.Path),
+					lg.String("request_id", GetRequestID(r.Context())),
+					lg.String("stack", stack),
+				)
+				if rw.status != 0 {
+					panic(http.ErrAbortHandler)
+				}
+				e := APIError{
+					Code:    ErrCodeInternal,
+					Message: http.StatusText(http.StatusInternalServerError),
+					Status:  http.StatusInternalServerError,
+				}
+				if cfg.Stack {
+					e.Details = map[string]string{"panic": fmt.Sprint(v), "stack": stack}
+				}
+				WriteJSONError(rw, e)
+			}()
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// RequestLogConfig holds optional RequestLogger settings.
+type RequestLogConfig struct {
+	// TrustProxy takes the remote IP from the first X-Forwarded-For entry.
+	// Enable it only behind a proxy that sets the header.
+	TrustProxy bool
+}
+
+// RequestLogger attaches a child of logger to the request context
+// (zlog.FromContext) carrying the request ID, method, path, remote IP and,
+// if the request has one, the W3C or B3 trace ID. Once the handler returns
+// it logs a "Request" line with the status, response size and latency: at
+// error level for 5xx, warn for 4xx and info otherwise. A nil logger uses
+// the one already attached to the request context. Without RequestID in
+// front of it, it assigns the request ID itself.
+func RequestLogger(logger lg.ZLogger, config ...RequestLogConfig) Middleware {
+	var cfg RequestLogConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ctx := r.Context()
+			id := GetRequestID(ctx)
+			if id == "" {
+				id = r.Header.Get(HeaderRequestID)
+				if !validRequestID(id) {
+					id = newRequestID()
+				}
+				w.Header().Set(HeaderRequestID, id)
+				ctx = context.WithValue(ctx, requestIDKey{}, id)
+			}
+			fields := []lg.Field{
+				lg.String("request_id", id),
+				lg.String("method", r.Method),
+				lg.String("path", r.URL.Path),
+				lg.String("remote_ip", remoteIP(r, cfg.TrustProxy)),
+			}
+			if trace := traceID(r); trace != "" {
+				fields = append(fields, lg.String("trace_id", trace))
+			}
+			reqLogger := requestLogger(logger, r).With(fields...)
+			rw := wrapWriter(w)
+			next.ServeHTTP(rw, r.WithContext(lg.Attach(ctx, reqLogger)))
+
+			status := rw.statusCode()
+			log := reqLogger.Info
+			switch {
+			case status >= 500:
+				log = reqLogger.Error
+			case status >= 400:
+				log = reqLogger.Warn
+			}
+			log("Request",
+				lg.Int("status", status),
+				lg.Any("bytes", rw.bytes),
+				lg.Any("latency", time.Since(start)),
+			)
+		})
+	}
+}
+
+// remoteIP returns the client address of r without the port.
+func remoteIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			first, _, _ := strings.Cut(fwd, ",")
+			if ip := strings.TrimSpace(first); net.ParseIP(ip) != nil {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// traceID returns the trace ID of a W3C traceparent or B3 header, or "".
+func traceID(r *http.Request) string {
+	// traceparent: version-traceid-parentid-flags
+	if parts := strings.Split(r.Header.Get("traceparent"), "-"); len(parts) == 4 && isTraceID(parts[1]) {
+		return parts[1]
+	}
+	if id := r.Header.Get("X-B3-TraceId"); isTraceID(id) {
+		return id
+	}
+	if b3 := r.Header.Get("b3"); b3 != "" {
+		if id, _, _ := strings.Cut(b3, "-"); isTraceID(id) {
+			return id
+		}
+	}
+	return ""
+}
+
+// isTraceID accepts 64- or 128-bit lowercase hex IDs that are not all zero.
+func isTraceID(id string) bool {
+	if len(id) != 16 && len(id) != 32 {
+		return false
+	}
+	zero := true
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+		zero = zero && c == '0'
+	}
+	return !zero
+}
+
+func requestLogger(logger lg.ZLogger, r *http.Request) lg.ZLogger {
+	if logger != nil {
+		return logger
+	}
+	return lg.FromContext(r.Context())
+}
+
+// Timeout cancels the request context after d and, if the handler has not
+// finished by then, responds 503 with an APIError of code timeout. It uses
+// http.TimeoutHandler, so responses are buffered and handlers cannot flush
+// or hijack the connection.
+func Timeout(d time.Duration) Middleware {
+	body, _ := json.Marshal(APIError{Code: ErrCodeTimeout, Message: "request timed out"})
+	return func(next http.Handler) http.Handler {
+		th := http.TimeoutHandler(next, d, string(body))
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			th.ServeHTTP(&timeoutWriter{ResponseWriter: w, ctx: ctx}, r.WithContext(ctx))
+		})
+	}
+}
+
+// timeoutWriter labels the timeout response written by http.TimeoutHandler
+// as JSON.
+type timeoutWriter struct {
+	http.ResponseWriter
+	ctx context.Context
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	if code == http.StatusServiceUnavailable && errors.Is(w.ctx.Err(), context.DeadlineExceeded) &&
+		w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *timeoutWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// responseWriter records the status and size of a response.
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func wrapWriter(w http.ResponseWriter) *responseWriter {
+	return &responseWriter{ResponseWriter: w}
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if w.status == 0 && code >= 200 { // 1xx responses are informational
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Flush supports streaming handlers behind the middleware.
+func (w *responseWriter) Flush() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack supports handlers that take over the connection, such as
+// websocket upgrades.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil && w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return conn, brw, err
+}
+
+// ReadFrom keeps the sendfile path of the underlying writer, used by
+// io.Copy and http.ServeContent.
+func (w *responseWriter) ReadFrom(r io.Reader) (int64, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := io.Copy(w.ResponseWriter, r)
+	w.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *responseWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// statusCode returns the response status; a handler that wrote nothing
+// sent 200.
+func (w *responseWriter) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
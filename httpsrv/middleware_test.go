@@ -0,0 +1,350 @@
+package srvx
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	lg "github.com/azargarov/go-utils/zlog"
+	"go.uber.org/zap/zapcore"
+)
+
+// captureLogger records log entries and their fields. Loggers derived with
+// With record into the same list.
+type captureLogger struct {
+	sink   *captureSink
+	fields []lg.Field // set by With
+}
+
+type captureSink struct {
+	mu      sync.Mutex
+	entries []logEntry
+}
+
+type logEntry struct {
+	level, msg string
+	fields     map[string]any
+}
+
+func newCaptureLogger() *captureLogger { return &captureLogger{sink: &captureSink{}} }
+
+func (c *captureLogger) log(level, msg string, fields []lg.Field) {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range append(append([]lg.Field(nil), c.fields...), fields...) {
+		f.AddTo(enc)
+	}
+	c.sink.mu.Lock()
+	c.sink.entries = append(c.sink.entries, logEntry{level: level, msg: msg, fields: enc.Fields})
+	c.sink.mu.Unlock()
+}
+
+func (c *captureLogger) Info(msg string, fields ...lg.Field)  { c.log("info", msg, fields) }
+func (c *captureLogger) Debug(msg string, fields ...lg.Field) { c.log("debug", msg, fields) }
+func (c *captureLogger) Error(msg string, fields ...lg.Field) { c.log("error", msg, fields) }
+func (c *captureLogger) Warn(msg string, fields ...lg.Field)  { c.log("warn", msg, fields) }
+func (c *captureLogger) With(fields ...lg.Field) lg.ZLogger {
+	return &captureLogger{sink: c.sink, fields: append(append([]lg.Field(nil), c.fields...), fields...)}
+}
+func (c *captureLogger) Sync() error                                    { return nil }
+func (c *captureLogger) RedirectStdLog(zapcore.Level) func()            { return func() {} }
+func (c *captureLogger) RedirectOutput(io.Writer, zapcore.Level) func() { return func() {} }
+
+func (c *captureLogger) all() []logEntry {
+	c.sink.mu.Lock()
+	defer c.sink.mu.Unlock()
+	return append([]logEntry(nil), c.sink.entries...)
+}
+
+func TestRequestID(t *testing.T) {
+	var seen string
+	h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = GetRequestID(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(HeaderRequestID, "abc-123")
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	if seen != "abc-123" || rr.Header().Get(HeaderRequestID) != "abc-123" {
+		t.Fatalf("incoming ID not propagated: ctx %q, header %q", seen, rr.Header().Get(HeaderRequestID))
+	}
+
+	for _, bad := range []string{"", "has space", "line\nbreak", strings.Repeat("x", maxRequestIDLen+1)} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(HeaderRequestID, bad)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		if len(seen) != 32 || seen == bad || rr.Header().Get(HeaderRequestID) != seen {
+			t.Fatalf("ID %q: generated %q, header %q", bad, seen, rr.Header().Get(HeaderRequestID))
+		}
+	}
+}
+
+func TestRecover(t *testing.T) {
+	logger := newCaptureLogger()
+	h := Recover(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+	rr := serve(h, http.MethodGet, "/explode")
+	if rr.Code != http.StatusInternalServerError || rr.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("status %d content type %q; want 500 JSON", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	var e APIError
+	if err := json.Unmarshal(rr.Body.Bytes(), &e); err != nil || e.Code != ErrCodeInternal || e.Details != nil {
+		t.Fatalf("body %q: %v", rr.Body.String(), err)
+	}
+	entries := logger.all()
+	if len(entries) != 1 || entries[0].level != "error" || entries[0].fields["panic"] != "boom" || entries[0].fields["path"] != "/explode" {
+		t.Fatalf("log entries = %+v", entries)
+	}
+	if stack, _ := entries[0].fields["stack"].(string); !strings.Contains(stack, "TestRecover") {
+		t.Fatalf("logged stack %q does not show the handler", stack)
+	}
+}
+
+func TestRecover_StackInDevMode(t *testing.T) {
+	h := Recover(lg.Discard, RecoverConfig{Stack: true})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(errors.New("boom"))
+	}))
+	rr := serve(h, http.MethodGet, "/")
+	var e struct {
+		Code    string            `json:"error"`
+		Details map[string]string `json:"details"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &e); err != nil {
+		t.Fatalf("body %q: %v", rr.Body.String(), err)
+	}
+	if e.Code != ErrCodeInternal || e.Details["panic"] != "boom" || !strings.Contains(e.Details["stack"], "TestRecover_StackInDevMode") {
+		t.Fatalf("error response = %+v", e)
+	}
+}
+
+func TestRecover_AfterWriteAborts(t *testing.T) {
+	logger := newCaptureLogger()
+	h := Recover(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("late")
+	}))
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Fatalf("recovered %v; want http.ErrAbortHandler", v)
+		}
+		if entries := logger.all(); len(entries) != 1 || entries[0].fields["panic"] != "late" {
+			t.Fatalf("log entries = %+v", entries)
+		}
+	}()
+	serve(h, http.MethodGet, "/")
+	t.Fatal("panic after the response started was swallowed")
+}
+
+func TestRecover_AfterWriteTruncatesConnection(t *testing.T) {
+	srv := httptest.NewServer(Recover(lg.Discard)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("partial")) // chunked: only an abort tells the client
+		w.(http.Flusher).Flush()
+		panic("late")
+	})))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		return // aborted before the headers arrived
+	}
+	defer resp.Body.Close()
+	if _, err := io.ReadAll(resp.Body); err == nil {
+		t.Fatal("truncated response read as complete")
+	}
+}
+
+func TestResponseWriter_HijackAndReadFrom(t *testing.T) {
+	logger := newCaptureLogger()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hijack", func(w http.ResponseWriter, r *http.Request) {
+		conn, brw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("Hijack: %v", err)
+			return
+		}
+		defer conn.Close()
+		_, _ = brw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 2\r\nConnection: close\r\n\r\nhi")
+		_ = brw.Flush()
+	})
+	mux.HandleFunc("/copy", func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(io.ReaderFrom); !ok {
+			t.Error("writer lost io.ReaderFrom")
+		}
+		_, _ = io.Copy(w, strings.NewReader("hello"))
+	})
+	srv := httptest.NewServer(Chain(mux, Recover(lg.Discard), RequestLogger(logger)))
+	defer srv.Close()
+
+	for path, want := range map[string]string{"/hijack": "hi", "/copy": "hello"} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != want {
+			t.Fatalf("GET %s = %q; want %q", path, body, want)
+		}
+	}
+	logged := 0
+	for _, e := range logger.all() {
+		logged++
+		switch e.fields["path"] {
+		case "/hijack":
+			if e.fields["status"] != int64(http.StatusSwitchingProtocols) {
+				t.Fatalf("hijacked request logged as %v", e.fields)
+			}
+		case "/copy":
+			if e.fields["status"] != int64(http.StatusOK) || e.fields["bytes"] != int64(5) {
+				t.Fatalf("copied response logged as %v", e.fields)
+			}
+		}
+	}
+	if logged != 2 {
+		t.Fatalf("logged %d requests; want 2", logged)
+	}
+}
+
+func TestRecover_ReraisesAbortHandler(t *testing.T) {
+	h := Recover(lg.Discard)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Fatalf("recovered %v; want http.ErrAbortHandler", v)
+		}
+	}()
+	serve(h, http.MethodGet, "/")
+	t.Fatal("ErrAbortHandler was swallowed")
+}
+
+func TestRequestLogger(t *testing.T) {
+	logger := newCaptureLogger()
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lg.FromContext(r.Context()).Info("handling")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("hello"))
+	}), RequestID, RequestLogger(logger))
+
+	req := httptest.NewRequest(http.MethodPost, "/items", nil)
+	req.RemoteAddr = "192.0.2.7:5123"
+	req.Header.Set(HeaderRequestID, "req-1")
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := logger.all()
+	if len(entries) != 2 {
+		t.Fatalf("log entries = %+v", entries)
+	}
+	for _, e := range entries {
+		f := e.fields
+		if f["method"] != "POST" || f["path"] != "/items" || f["request_id"] != "req-1" ||
+			f["remote_ip"] != "192.0.2.7" || f["trace_id"] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Fatalf("%q fields = %v", e.msg, f)
+		}
+	}
+	if entries[0].msg != "handling" {
+		t.Fatalf("handler log = %+v", entries[0])
+	}
+	e := entries[1]
+	if e.msg != "Request" || e.level != "info" || e.fields["status"] != int64(201) || e.fields["bytes"] != int64(5) {
+		t.Fatalf("access log = %+v", e)
+	}
+	if _, ok := e.fields["latency"]; !ok {
+		t.Fatalf("access log has no latency: %v", e.fields)
+	}
+}
+
+func TestRequestLogger_LevelsAndRequestID(t *testing.T) {
+	for _, tc := range []struct {
+		status int
+		level  string
+	}{{200, "info"}, {404, "warn"}, {503, "error"}} {
+		logger := newCaptureLogger()
+		h := RequestLogger(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tc.status)
+		}))
+		rr := serve(h, http.MethodGet, "/")
+		entries := logger.all()
+		if len(entries) != 1 || entries[0].level != tc.level {
+			t.Fatalf("status %d: entries = %+v; want one %s line", tc.status, entries, tc.level)
+		}
+		id := rr.Header().Get(HeaderRequestID)
+		if id == "" || entries[0].fields["request_id"] != id {
+			t.Fatalf("request ID %q not assigned or logged: %v", id, entries[0].fields)
+		}
+		if _, ok := entries[0].fields["trace_id"]; ok {
+			t.Fatalf("trace_id logged without a trace header: %v", entries[0].fields)
+		}
+	}
+}
+
+func TestRemoteIP(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "[2001:db8::1]:443"
+	req.Header.Set("X-Forwarded-For", "203.0.113.9, 10.0.0.1")
+	if ip := remoteIP(req, false); ip != "2001:db8::1" {
+		t.Fatalf("remoteIP = %q", ip)
+	}
+	if ip := remoteIP(req, true); ip != "203.0.113.9" {
+		t.Fatalf("remoteIP behind proxy = %q", ip)
+	}
+	req.Header.Set("X-Forwarded-For", "not-an-ip")
+	if ip := remoteIP(req, true); ip != "2001:db8::1" {
+		t.Fatalf("remoteIP with bad X-Forwarded-For = %q", ip)
+	}
+}
+
+func TestTraceID(t *testing.T) {
+	for _, tc := range []struct {
+		header, value, want string
+	}{
+		{"traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{"traceparent", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", ""},
+		{"traceparent", "00-NOTHEX-00f067aa0ba902b7-01", ""},
+		{"X-B3-TraceId", "463ac35c9f6413ad", "463ac35c9f6413ad"},
+		{"b3", "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1", "80f198ee56343ba864fe8b2a57d3eff7"},
+		{"b3", "0", ""},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(tc.header, tc.value)
+		if got := traceID(req); got != tc.want {
+			t.Errorf("%s: %q: traceID = %q; want %q", tc.header, tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	rr := serve(Timeout(20*time.Millisecond)(slow), http.MethodGet, "/")
+	if rr.Code != http.StatusServiceUnavailable || rr.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("status %d content type %q; want 503 JSON", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	var e APIError
+	if err := json.Unmarshal(rr.Body.Bytes(), &e); err != nil || e.Code != ErrCodeTimeout {
+		t.Fatalf("body %q: %v", rr.Body.String(), err)
+	}
+
+	fast := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("ok"))
+	})
+	rr = serve(Timeout(time.Second)(fast), http.MethodGet, "/")
+	if rr.Code != http.StatusOK || rr.Body.String() != "ok" || rr.Header().Get("Content-Type") != "text/plain" {
+		t.Fatalf("fast handler: status %d body %q type %q", rr.Code, rr.Body.String(), rr.Header().Get("Content-Type"))
+	}
+}
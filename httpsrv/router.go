@@ -0,0 +1,114 @@
+package srvx
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Middleware wraps a handler with cross-cutting behavior.
+type Middleware func(http.Handler) http.Handler
+
+// Chain wraps h with mws so that the first middleware is the outermost one.
+func Chain(h http.Handler, mws ...Middleware) http.Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// Router registers routes on an http.ServeMux, using its patterns
+// ("GET /users/{id}"), and groups them under path prefixes with their own
+// middleware stacks.
+//
+// Middleware added with Use on the root router wraps the whole mux, so it
+// also runs for unmatched routes; middleware of a group wraps the group's
+// routes only. Use applies to routes registered after it. Configure the
+// router before serving; it is not safe to register routes concurrently
+// with requests.
+type Router struct {
+	mux     *http.ServeMux
+	root    *Router
+	prefix  string
+	mws     []Middleware
+	handler http.Handler // root only: mws around mux
+}
+
+// NewRouter returns an empty router with the given root middleware.
+func NewRouter(mws ...Middleware) *Router {
+	r := &Router{mux: http.NewServeMux()}
+	r.root = r
+	r.Use(mws...)
+	return r
+}
+
+// Use appends middleware to the router's stack.
+func (r *Router) Use(mws ...Middleware) {
+	r.mws = append(r.mws, mws...)
+	if r.root == r {
+		r.handler = Chain(r.mux, r.mws...)
+	}
+}
+
+// Group returns a sub-router whose routes are registered under prefix and
+// wrapped in its middleware after the parent group's. fn, if not nil, is
+// called with the group to register its routes.
+func (r *Router) Group(prefix string, fn func(g *Router)) *Router {
+	g := &Router{
+		mux:    r.mux,
+		root:   r.root,
+		prefix: r.prefix + strings.TrimSuffix(prefix, "/"),
+	}
+	if r != r.root {
+		g.mws = append([]Middleware(nil), r.mws...)
+	}
+	if fn != nil {
+		fn(g)
+	}
+	return g
+}
+
+// Handle registers h for pattern, which takes the group's prefix before its
+// path and the group's middleware around h.
+func (r *Router) Handle(pattern string, h http.Handler) {
+	if r != r.root {
+		h = Chain(h, r.mws...)
+	}
+	r.mux.Handle(r.pattern(pattern), h)
+}
+
+// HandleFunc registers fn for pattern like Handle.
+func (r *Router) HandleFunc(pattern string, fn http.HandlerFunc) {
+	r.Handle(pattern, fn)
+}
+
+// Get, Post, Put, Patch and Delete register fn for path with that method.
+func (r *Router) Get(path string, fn http.HandlerFunc)    { r.HandleFunc("GET "+path, fn) }
+func (r *Router) Post(path string, fn http.HandlerFunc)   { r.HandleFunc("POST "+path, fn) }
+func (r *Router) Put(path string, fn http.HandlerFunc)    { r.HandleFunc("PUT "+path, fn) }
+func (r *Router) Patch(path string, fn http.HandlerFunc)  { r.HandleFunc("PATCH "+path, fn) }
+func (r *Router) Delete(path string, fn http.HandlerFunc) { r.HandleFunc("DELETE "+path, fn) }
+
+// ServeHTTP dispatches the request through the root middleware and the mux.
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.root.handler.ServeHTTP(w, req)
+}
+
+// pattern inserts the group's prefix between the method and host of a
+// ServeMux pattern and its path.
+func (r *Router) pattern(p string) string {
+	if r.prefix == "" {
+		return p
+	}
+	method, rest, ok := strings.Cut(p, " ")
+	if !ok {
+		method, rest = "", p
+	} else {
+		method += " "
+		rest = strings.TrimLeft(rest, " \t")
+	}
+	host, path := "", rest
+	if i := strings.Index(rest, "/"); i >= 0 {
+		host, path = rest[:i], rest[i:]
+	}
+	return method + host + r.prefix + path
+}
@@ -0,0 +1,105 @@
+package srvx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// tag returns middleware that appends name to the X-Trace response header.
+func tag(name string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Trace", name)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func serve(h http.Handler, method, target string) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(method, target, nil))
+	return rr
+}
+
+func TestChain_Order(t *testing.T) {
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), tag("a"), tag("b"))
+	rr := serve(h, http.MethodGet, "/")
+	if got := strings.Join(rr.Header().Values("X-Trace"), ","); got != "a,b" {
+		t.Fatalf("middleware order %q; want a,b", got)
+	}
+}
+
+func TestRouter_GroupsAndMiddleware(t *testing.T) {
+	r := NewRouter(tag("root"))
+	r.Get("/healthz", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	r.Group("/api/", func(api *Router) {
+		api.Use(tag("api"))
+		api.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("user " + r.PathValue("id")))
+		})
+		api.Group("/admin", func(admin *Router) {
+			admin.Use(tag("admin"))
+			admin.Delete("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+			})
+		})
+	})
+
+	cases := []struct {
+		method, target string
+		status         int
+		trace, body    string
+	}{
+		{http.MethodGet, "/healthz", http.StatusNoContent, "root", ""},
+		{http.MethodGet, "/api/users/7", http.StatusOK, "root,api", "user 7"},
+		{http.MethodDelete, "/api/admin/users/7", http.StatusAccepted, "root,api,admin", ""},
+		{http.MethodPost, "/api/users/7", http.StatusMethodNotAllowed, "root", ""},
+		{http.MethodGet, "/users/7", http.StatusNotFound, "root", ""},
+	}
+	for _, c := range cases {
+		rr := serve(r, c.method, c.target)
+		if rr.Code != c.status {
+			t.Errorf("%s %s: status %d; want %d", c.method, c.target, rr.Code, c.status)
+		}
+		if got := strings.Join(rr.Header().Values("X-Trace"), ","); got != c.trace {
+			t.Errorf("%s %s: middleware %q; want %q", c.method, c.target, got, c.trace)
+		}
+		if c.body != "" && rr.Body.String() != c.body {
+			t.Errorf("%s %s: body %q; want %q", c.method, c.target, rr.Body.String(), c.body)
+		}
+	}
+}
+
+func TestRouter_GroupMiddlewareAppliesToLaterRoutes(t *testing.T) {
+	r := NewRouter()
+	g := r.Group("/v1", nil)
+	g.Get("/before", func(w http.ResponseWriter, r *http.Request) {})
+	g.Use(tag("v1"))
+	g.Get("/after", func(w http.ResponseWriter, r *http.Request) {})
+
+	if got := serve(r, http.MethodGet, "/v1/before").Header().Get("X-Trace"); got != "" {
+		t.Fatalf("route registered before Use got middleware %q", got)
+	}
+	if got := serve(r, http.MethodGet, "/v1/after").Header().Get("X-Trace"); got != "v1" {
+		t.Fatalf("route registered after Use got middleware %q; want v1", got)
+	}
+}
+
+func TestRouter_Pattern(t *testing.T) {
+	g := NewRouter().Group("/api", nil)
+	cases := map[string]string{
+		"/users":                "/api/users",
+		"GET /users/{id}":       "GET /api/users/{id}",
+		"POST  /users":          "POST /api/users",
+		"example.com/users":     "example.com/api/users",
+		"GET example.com/{$}":   "GET example.com/api/{$}",
+		"DELETE /users/{id...}": "DELETE /api/users/{id...}",
+	}
+	for in, want := range cases {
+		if got := g.pattern(in); got != want {
+			t.Errorf("pattern(%q) = %q; want %q", in, got, want)
+		}
+	}
+}
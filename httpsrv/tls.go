@@ -0,0 +1,221 @@
+package srvx
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	lg "github.com/azargarov/go-utils/zlog"
+)
+
+const defaultCertReloadInterval = 10 * time.Second
+
+// TLSConfig makes RunServer serve HTTPS. The certificate comes either from
+// CertFile and KeyFile, which are reloaded when they change on disk, or
+// from Certificates held in memory.
+type TLSConfig struct {
+	// CertFile and KeyFile are PEM files of the server certificate chain and
+	// its key. They are checked every ReloadInterval and the new pair is
+	// served from the next handshake on, without a restart.
+	CertFile string
+	KeyFile  string
+	// Certificates are in-memory certificates, used when no files are set.
+	// Build them with tls.X509KeyPair or tls.LoadX509KeyPair.
+	Certificates []tls.Certificate
+
+	// ClientCAFile (PEM) or ClientCAs turn on mutual TLS: clients must
+	// present a certificate signed by one of these CAs.
+	ClientCAFile string
+	ClientCAs    *x509.CertPool
+	// ClientAuth overrides the client certificate policy. Default
+	// tls.RequireAndVerifyClientCert when a client CA is set.
+	ClientAuth tls.ClientAuthType
+
+	// MinVersion is the lowest accepted protocol version. Default TLS 1.2.
+	MinVersion uint16
+	// CipherSuites restricts the TLS 1.2 cipher suites; TLS 1.3 suites are
+	// not configurable. Default Go's secure list. Suites listed by
+	// tls.InsecureCipherSuites are rejected.
+	CipherSuites []uint16
+
+	// ReloadInterval is how often CertFile and KeyFile are checked for
+	// changes. Default 10s.
+	ReloadInterval time.Duration
+
+	// RedirectPort, if set, starts a plain HTTP listener on Addr and this
+	// port that redirects every request to HTTPS.
+	RedirectPort string
+}
+
+// build returns the tls.Config of c and, for file certificates, the
+// reloader serving them.
+func (c *TLSConfig) build(logger lg.ZLogger) (*tls.Config, *certReloader, error) {
+	hasFiles := c.CertFile != "" || c.KeyFile != ""
+	switch {
+	case hasFiles && (c.CertFile == "" || c.KeyFile == ""):
+		return nil, nil, errors.New("srvx: TLS needs both CertFile and KeyFile")
+	case hasFiles && len(c.Certificates) > 0:
+		return nil, nil, errors.New("srvx: TLS takes either certificate files or Certificates, not both")
+	case !hasFiles && len(c.Certificates) == 0:
+		return nil, nil, errors.New("srvx: TLS needs a certificate")
+	}
+	for _, id := range c.CipherSuites {
+		for _, s := range tls.InsecureCipherSuites() {
+			if s.ID == id {
+				return nil, nil, fmt.Errorf("srvx: insecure TLS cipher suite %s", s.Name)
+			}
+		}
+	}
+
+	cfg := &tls.Config{
+		MinVersion:   c.MinVersion,
+		CipherSuites: c.CipherSuites,
+		ClientAuth:   c.ClientAuth,
+	}
+	if cfg.MinVersion == 0 {
+		cfg.MinVersion = tls.VersionTLS12
+	}
+
+	cfg.ClientCAs = c.ClientCAs
+	if c.ClientCAFile != "" {
+		pem, err := os.ReadFile(c.ClientCAFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("srvx: read client CA: %w", err)
+		}
+		if cfg.ClientCAs == nil {
+			cfg.ClientCAs = x509.NewCertPool()
+		} else {
+			cfg.ClientCAs = cfg.ClientCAs.Clone()
+		}
+		if !cfg.ClientCAs.AppendCertsFromPEM(pem) {
+			return nil, nil, fmt.Errorf("srvx: no certificates in client CA file %s", c.ClientCAFile)
+		}
+	}
+	if cfg.ClientCAs != nil && cfg.ClientAuth == tls.NoClientCert {
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	if !hasFiles {
+		cfg.Certificates = c.Certificates
+		return cfg, nil, nil
+	}
+	r := &certReloader{certFile: c.CertFile, keyFile: c.KeyFile, logger: logger}
+	if err := r.load(); err != nil {
+		return nil, nil, err
+	}
+	cfg.GetCertificate = r.GetCertificate
+	return cfg, r, nil
+}
+
+// certReloader serves a certificate loaded from files and reloads it when
+// the files change.
+type certReloader struct {
+	certFile, keyFile string
+	logger            lg.ZLogger
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	certMod fileVersion
+	keyMod  fileVersion
+}
+
+// fileVersion tells whether a file has changed since it was loaded.
+type fileVersion struct {
+	mod  time.Time
+	size int64
+}
+
+func statVersion(path string) (fileVersion, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fileVersion{}, err
+	}
+	return fileVersion{mod: fi.ModTime(), size: fi.Size()}, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// load reads the certificate pair if either file changed since the last
+// successful load. On error the current certificate stays in use.
+func (r *certReloader) load() error {
+	certMod, err := statVersion(r.certFile)
+	if err != nil {
+		return fmt.Errorf("srvx: TLS certificate: %w", err)
+	}
+	keyMod, err := statVersion(r.keyFile)
+	if err != nil {
+		return fmt.Errorf("srvx: TLS key: %w", err)
+	}
+	r.mu.RLock()
+	unchanged := r.cert != nil && certMod == r.certMod && keyMod == r.keyMod
+	r.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("srvx: load TLS certificate: %w", err)
+	}
+	r.mu.Lock()
+	r.cert, r.certMod, r.keyMod = &cert, certMod, keyMod
+	r.mu.Unlock()
+	return nil
+}
+
+// watch reloads the certificate every interval until ctx is done. A pair
+// that fails to load, for instance while only one file has been replaced,
+// is retried on the next tick.
+func (r *certReloader) watch(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			r.mu.RLock()
+			prev := r.cert
+			r.mu.RUnlock()
+			if err := r.load(); err != nil {
+				r.logger.Error("TLS certificate reload failed", lg.Error("error", err))
+				continue
+			}
+			r.mu.RLock()
+			reloaded := r.cert != prev
+			r.mu.RUnlock()
+			if reloaded {
+				r.logger.Info("TLS certificate reloaded", lg.String("cert_file", r.certFile))
+			}
+		}
+	}
+}
+
+// redirectHandler sends every request to the same host and path over HTTPS
+// on httpsPort.
+func redirectHandler(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		} else if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+			host = "[" + host + "]"
+		}
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+	})
+}
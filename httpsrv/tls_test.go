@@ -0,0 +1,282 @@
+package srvx
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	lg "github.com/azargarov/go-utils/zlog"
+)
+
+// testCert is a certificate and key in PEM, plus the parsed pair.
+type testCert struct {
+	certPEM, keyPEM []byte
+	cert            *x509.Certificate
+	key             *ecdsa.PrivateKey
+}
+
+func (c testCert) pair(t testing.TB) tls.Certificate {
+	t.Helper()
+	pair, err := tls.X509KeyPair(c.certPEM, c.keyPEM)
+	if err != nil {
+		t.Fatalf("key pair: %v", err)
+	}
+	return pair
+}
+
+// newTestCert issues a certificate for 127.0.0.1 named cn, signed by parent
+// or self-signed as a CA when parent is nil.
+func newTestCert(t testing.TB, cn string, parent *testCert) testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	return testCert{
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		cert:    cert,
+		key:     key,
+	}
+}
+
+// writeCert writes c to cert.pem and key.pem in dir, moving their
+// modification time forward so a reload sees the change.
+func writeCert(t testing.TB, dir string, c testCert, mod time.Time) (certFile, keyFile string) {
+	t.Helper()
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	for path, data := range map[string][]byte{certFile: c.certPEM, keyFile: c.keyPEM} {
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatalf("chtimes %s: %v", path, err)
+		}
+	}
+	return certFile, keyFile
+}
+
+func TestTLSConfig_BuildErrors(t *testing.T) {
+	ca := newTestCert(t, "ca", nil)
+	certFile, _ := writeCert(t, t.TempDir(), ca, time.Now())
+	for name, c := range map[string]TLSConfig{
+		"no certificate": {},
+		"no key file":    {CertFile: certFile},
+		"files and certificates": {
+			CertFile: certFile, KeyFile: certFile, Certificates: []tls.Certificate{ca.pair(t)},
+		},
+		"insecure cipher": {
+			Certificates: []tls.Certificate{ca.pair(t)},
+			CipherSuites: []uint16{tls.TLS_RSA_WITH_RC4_128_SHA},
+		},
+		"bad client CA": {Certificates: []tls.Certificate{ca.pair(t)}, ClientCAFile: filepath.Join(t.TempDir(), "missing.pem")},
+	} {
+		if _, _, err := c.build(lg.Discard); err == nil {
+			t.Errorf("%s: build succeeded", name)
+		}
+	}
+}
+
+func TestTLSConfig_BuildDefaults(t *testing.T) {
+	ca := newTestCert(t, "ca", nil)
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(caFile, ca.certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c := TLSConfig{Certificates: []tls.Certificate{ca.pair(t)}, ClientCAFile: caFile}
+	cfg, reloader, err := c.build(lg.Discard)
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	if reloader != nil || len(cfg.Certificates) != 1 {
+		t.Fatalf("in-memory certificate not used directly")
+	}
+	if cfg.MinVersion != tls.VersionTLS12 || cfg.ClientAuth != tls.RequireAndVerifyClientCert || cfg.ClientCAs == nil {
+		t.Fatalf("min version %x, client auth %v, client CAs %v", cfg.MinVersion, cfg.ClientAuth, cfg.ClientCAs)
+	}
+
+	c.ClientAuth = tls.VerifyClientCertIfGiven
+	c.MinVersion = tls.VersionTLS13
+	if cfg, _, _ = c.build(lg.Discard); cfg.ClientAuth != tls.VerifyClientCertIfGiven || cfg.MinVersion != tls.VersionTLS13 {
+		t.Fatalf("overrides ignored: client auth %v, min version %x", cfg.ClientAuth, cfg.MinVersion)
+	}
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	first := newTestCert(t, "first", nil)
+	start := time.Now().Add(-time.Minute)
+	certFile, keyFile := writeCert(t, dir, first, start)
+
+	cfg, r, err := (&TLSConfig{CertFile: certFile, KeyFile: keyFile}).build(lg.Discard)
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	served := func() string {
+		c, _ := cfg.GetCertificate(nil)
+		leaf, err := x509.ParseCertificate(c.Certificate[0])
+		if err != nil {
+			t.Fatalf("parse served certificate: %v", err)
+		}
+		return leaf.Subject.CommonName
+	}
+	if got := served(); got != "first" {
+		t.Fatalf("serving %q", got)
+	}
+
+	second := newTestCert(t, "second", nil)
+	writeCert(t, dir, second, start.Add(time.Second))
+	if err := r.load(); err != nil || served() != "second" {
+		t.Fatalf("after rotation: serving %q, err %v", served(), err)
+	}
+
+	// a half-replaced pair keeps the last good certificate
+	if err := os.WriteFile(keyFile, first.keyPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.load(); err == nil || served() != "second" {
+		t.Fatalf("mismatched pair: serving %q, err %v", served(), err)
+	}
+}
+
+func TestRedirectHandler(t *testing.T) {
+	for _, tc := range []struct {
+		port, host, target, want string
+	}{
+		{"443", "example.com", "/a?b=c", "https://example.com/a?b=c"},
+		{"443", "example.com:80", "/", "https://example.com/"},
+		{"8443", "example.com:8080", "/x", "https://example.com:8443/x"},
+		{"443", "[2001:db8::1]:80", "/", "https://[2001:db8::1]/"},
+	} {
+		req := httptest.NewRequest(http.MethodPost, tc.target, nil)
+		req.Host = tc.host
+		rr := httptest.NewRecorder()
+		redirectHandler(tc.port).ServeHTTP(rr, req)
+		if rr.Code != http.StatusPermanentRedirect || rr.Header().Get("Location") != tc.want {
+			t.Errorf("%s%s: status %d location %q; want %q", tc.host, tc.target, rr.Code, rr.Header().Get("Location"), tc.want)
+		}
+	}
+}
+
+func freePort(t testing.TB) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	return strconvI(ln.Addr().(*net.TCPAddr).Port)
+}
+
+func TestRunServer_MutualTLSAndRedirect(t *testing.T) {
+	ca := newTestCert(t, "ca", nil)
+	server := newTestCert(t, "server", &ca)
+	client := newTestCert(t, "client", &ca)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+
+	cfg := shortCfg()
+	cfg.Port = freePort(t)
+	redirectPort := freePort(t)
+	cfg.TLS = &TLSConfig{
+		Certificates: []tls.Certificate{server.pair(t)},
+		ClientCAs:    pool,
+		RedirectPort: redirectPort,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Client", r.TLS.PeerCertificates[0].Subject.CommonName)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	done := make(chan error, 1)
+	go func() { done <- RunServer(mux, cfg) }()
+	time.Sleep(100 * time.Millisecond)
+
+	get := func(certs []tls.Certificate) (*http.Response, error) {
+		c := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, Certificates: certs}}}
+		defer c.CloseIdleConnections()
+		return c.Get("https://127.0.0.1:" + cfg.Port + "/ok")
+	}
+	resp, err := get([]tls.Certificate{client.pair(t)})
+	if err != nil {
+		t.Fatalf("mTLS request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent || resp.Header.Get("X-Client") != "client" {
+		t.Fatalf("status %d client %q", resp.StatusCode, resp.Header.Get("X-Client"))
+	}
+	if resp, err := get(nil); err == nil {
+		resp.Body.Close()
+		t.Fatal("request without a client certificate succeeded")
+	}
+
+	noFollow := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err = noFollow.Get("http://127.0.0.1:" + redirectPort + "/ok?x=1")
+	if err != nil {
+		t.Fatalf("redirect request: %v", err)
+	}
+	resp.Body.Close()
+	if want := "https://127.0.0.1:" + cfg.Port + "/ok?x=1"; resp.StatusCode != http.StatusPermanentRedirect || resp.Header.Get("Location") != want {
+		t.Fatalf("redirect: status %d location %q; want %q", resp.StatusCode, resp.Header.Get("Location"), want)
+	}
+
+	_ = syscall.Kill(os.Getpid(), syscall.SIGINT)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("RunServer: %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("RunServer did not exit after SIGINT")
+	}
+}
+
+func TestRunServer_TLSConfigError(t *testing.T) {
+	cfg := shortCfg()
+	cfg.TLS = &TLSConfig{}
+	if err := RunServer(nil, cfg); err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Fatalf("RunServer = %v; want a certificate error", err)
+	}
+}
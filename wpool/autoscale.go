@@ -0,0 +1,94 @@
+package workerpool
+
+import (
+	"time"
+)
+
+const defaultIdleTimeout = 30 * time.Second
+
+func (p *Pool[T]) initScaling(maxWorkers int, cfg Config[T]) {
+	minWorkers := cfg.MinWorkers
+	if minWorkers <= 0 || minWorkers > maxWorkers {
+		minWorkers = maxWorkers
+	}
+	p.maxWorkers.Store(int32(maxWorkers))
+	p.minWorkers.Store(int32(minWorkers))
+
+	p.idleTimeout = cfg.IdleTimeout
+	if p.idleTimeout <= 0 {
+		p.idleTimeout = defaultIdleTimeout
+	}
+	p.scaleUpQueue = cfg.ScaleUpQueueLength
+	if p.scaleUpQueue <= 0 {
+		p.scaleUpQueue = 1
+	}
+	p.scaleUpWait = cfg.ScaleUpWait
+
+	resized := make(chan struct{})
+	p.resized.Store(&resized)
+}
+
+// Workers returns the number of live worker goroutines, busy or idle.
+func (p *Pool[T]) Workers() int { return int(p.workers.Load()) }
+
+// Resize sets the maximum number of workers to n (at least 1). A fixed-size
+// pool stays fixed at the new size; an autoscaling pool lowers its minimum if
+// it exceeds n. Surplus workers exit once their current job finishes.
+// The queue capacity is not changed.
+func (p *Pool[T]) Resize(n int) {
+	if n <= 0 {
+		n = 1
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.minWorkers.Load() == p.maxWorkers.Load() || p.minWorkers.Load() > int32(n) {
+		p.minWorkers.Store(int32(n))
+	}
+	p.maxWorkers.Store(int32(n))
+
+	// wake idle workers so surplus ones retire
+	next := make(chan struct{})
+	close(*p.resized.Swap(&next))
+
+	if p.isClosed {
+		return
+	}
+	for p.workers.Load() < p.minWorkers.Load() {
+		p.spawn()
+	}
+}
+
+// spawn starts a worker. It must not race with the final wg.Wait: callers are
+// the dispatcher (which holds its own wg count) or hold p.mu with the pool open.
+func (p *Pool[T]) spawn() {
+	p.workers.Add(1)
+	p.wg.Add(1)
+	go p.worker()
+}
+
+// growAfter reports whether the pool may grow to serve t, and after how long.
+func (p *Pool[T]) growAfter(t *task[T], queued int) (time.Duration, bool) {
+	if p.workers.Load() >= p.maxWorkers.Load() {
+		return 0, false
+	}
+	if queued >= p.scaleUpQueue {
+		return 0, true
+	}
+	if p.scaleUpWait > 0 {
+		return p.scaleUpWait - time.Since(t.enqueued), true
+	}
+	return 0, false
+}
+
+// retire lets the calling worker exit if more than limit workers are live.
+func (p *Pool[T]) retire(limit int32) bool {
+	for {
+		n := p.workers.Load()
+		if n <= limit {
+			return false
+		}
+		if p.workers.CompareAndSwap(n, n-1) {
+			return true
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package workerpool
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func waitWorkers(t *testing.T, p *Pool[int], want int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for p.Workers() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("workers = %d; want %d", p.Workers(), want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPoolFixedSize(t *testing.T) {
+	p := NewPool[int](3, fastRetry)
+	defer p.Stop()
+	waitWorkers(t, p, 3)
+}
+
+func TestPoolScalesUpAndDown(t *testing.T) {
+	p := NewPool[int](4, fastRetry, Config[int]{MinWorkers: 1, IdleTimeout: 20 * time.Millisecond})
+	defer p.Stop()
+	waitWorkers(t, p, 1)
+
+	release := make(chan struct{})
+	var started sync.WaitGroup
+	started.Add(4)
+	for i := 0; i < 4; i++ {
+		_ = p.Submit(Job[int]{Fn: func(int) error {
+			started.Done()
+			<-release
+			return nil
+		}})
+	}
+	started.Wait()
+	if got := p.Workers(); got != 4 {
+		t.Fatalf("workers under load = %d; want 4", got)
+	}
+	close(release)
+	waitWorkers(t, p, 1)
+}
+
+func TestPoolScaleUpWait(t *testing.T) {
+	p := NewPool[int](2, fastRetry, Config[int]{
+		MinWorkers:         1,
+		ScaleUpQueueLength: 10,
+		ScaleUpWait:        20 * time.Millisecond,
+	})
+	defer p.Stop()
+
+	release := make(chan struct{})
+	defer close(release)
+	_ = p.Submit(Job[int]{Fn: func(int) error { <-release; return nil }})
+	done := make(chan struct{})
+	_ = p.Submit(Job[int]{Fn: func(int) error { close(done); return nil }})
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("waiting job did not get a new worker")
+	}
+}
+
+func TestPoolResize(t *testing.T) {
+	p := NewPool[int](2, fastRetry)
+	defer p.Stop()
+	waitWorkers(t, p, 2)
+
+	p.Resize(5)
+	waitWorkers(t, p, 5)
+	p.Resize(1)
+	waitWorkers(t, p, 1)
+
+	done := make(chan struct{})
+	_ = p.Submit(Job[int]{Fn: func(int) error { close(done); return nil }})
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("job did not run after Resize")
+	}
+}
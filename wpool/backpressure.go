@@ -0,0 +1,149 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+)
+
+// OverflowPolicy selects what a submission does when the queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits for a free slot. Submit gives up when Job.Ctx is
+	// done and SubmitContext also when its ctx is; TrySubmit fails at once.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowReject fails the submission with ErrQueueFull.
+	OverflowReject
+	// OverflowDropOldest evicts the longest-queued job to make room. The
+	// evicted job completes as StatusCanceled with ErrJobDropped.
+	OverflowDropOldest
+	// OverflowDropNewest sheds the incoming job: the submission succeeds
+	// but the job never runs and its handle completes as StatusCanceled
+	// with ErrJobDropped.
+	OverflowDropNewest
+)
+
+var (
+	// ErrQueueFull is returned when a job cannot be queued without waiting.
+	ErrQueueFull = errors.New("workerpool: queue full")
+	// ErrJobDropped is the Result error of a job shed by a drop policy.
+	ErrJobDropped = errors.New("workerpool: job dropped")
+)
+
+// SubmitContext queues a job like Submit but stops waiting for a queue slot
+// once ctx is done, returning its error.
+func (p *Pool[T]) SubmitContext(ctx context.Context, job Job[T]) error {
+	_, err := p.submit(ctx, &job, true)
+	return err
+}
+
+// acquire reserves a queue slot for t, and a slot of its tenant if the
+// tenant has a queue limit, according to the overflow policy. It reports
+// false with a nil error when OverflowDropNewest shed t.
+func (p *Pool[T]) acquire(ctx context.Context, t *task[T], block bool) (bool, error) {
+	ts := p.tenantSlots(t.job.Tenant)
+	if ts != nil {
+		if ok, err := p.reserve(ctx, t, ts, block, true); !ok || err != nil {
+			return ok, err
+		}
+		t.tslots = ts
+	}
+	ok, err := p.reserve(ctx, t, p.slots, block, false)
+	if (!ok || err != nil) && ts != nil {
+		<-ts
+		t.tslots = nil
+	}
+	return ok, err
+}
+
+// reserve takes one slot of sem for t. With perTenant set, sem is the slot
+// pool of t's tenant and OverflowDropOldest evicts from that tenant only.
+func (p *Pool[T]) reserve(ctx context.Context, t *task[T], sem chan struct{}, block, perTenant bool) (bool, error) {
+	select {
+	case sem <- struct{}{}:
+		return true, nil
+	default:
+	}
+	switch p.overflow {
+	case OverflowReject:
+		return false, ErrQueueFull
+	case OverflowDropNewest:
+		return false, nil
+	case OverflowDropOldest:
+		if p.evict(t.job.Tenant, perTenant) {
+			return true, nil
+		}
+		// every slot belongs to a job parked behind its SerialKey
+		return false, ErrQueueFull
+	}
+	if !block {
+		return false, ErrQueueFull
+	}
+	select {
+	case sem <- struct{}{}:
+		return true, nil
+	case <-p.closed:
+		return false, ErrPoolClosed
+	case <-ctx.Done():
+		return false, ctx.Err()
+	case <-t.job.Ctx.Done():
+		return false, t.job.Ctx.Err()
+	}
+}
+
+// evict drops the longest-queued job holding a slot, of tenant only if
+// perTenant is set, and hands the caller its pool or tenant slot
+// respectively. It reports false if no queued job holds one.
+func (p *Pool[T]) evict(tenant string, perTenant bool) bool {
+	p.mu.Lock()
+	var victim *task[T]
+	p.queue.each(func(t *task[T]) {
+		if !t.slot || (perTenant && t.job.Tenant != tenant) {
+			return
+		}
+		if victim == nil || t.seq < victim.seq {
+			victim = t
+		}
+	})
+	if victim == nil {
+		p.mu.Unlock()
+		return false
+	}
+	p.queue.remove(victim)
+	victim.reclaimed = true // the dispatcher may already be offering it
+	victim.slot = false
+	p.mu.Unlock()
+	switch {
+	case perTenant:
+		<-p.slots
+	case victim.tslots != nil:
+		<-victim.tslots
+	}
+	victim.tslots = nil
+	p.drop(victim)
+	return true
+}
+
+// release frees the queue slots held by t.
+func (p *Pool[T]) release(t *task[T]) {
+	<-p.slots
+	if t.tslots != nil {
+		<-t.tslots
+		t.tslots = nil
+	}
+}
+
+// drop completes a job shed by the overflow policy without running it.
+func (p *Pool[T]) drop(t *task[T]) {
+	p.metrics.dropped.Add(1)
+	if m := p.tenantMetrics(t.job.Tenant); m != nil {
+		m.dropped.Add(1)
+	}
+	p.jobLog(t.job.Ctx, t).log(LogDiscard, "Job dropped")
+	p.ack(t)
+	p.releaseSerial(t)
+	if t.job.CleanupFunc != nil {
+		t.job.CleanupFunc()
+	}
+	p.finish(t, Result[T]{Status: StatusCanceled, Err: ErrJobDropped})
+}
@@ -0,0 +1,141 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fullPool returns a single-worker pool with a queue of two whose worker is
+// blocked until release is called, and whose queue holds jobs 1 and 2.
+func fullPool(t *testing.T, overflow OverflowPolicy) (p *Pool[int], queued []*Handle[int], ran func() []int, release func()) {
+	t.Helper()
+	p = NewPool[int](1, fastRetry, Config[int]{QueueCapacity: 2, Overflow: overflow})
+
+	var mu sync.Mutex
+	var order []int
+	gate := make(chan struct{})
+	started := make(chan struct{})
+	if err := p.Submit(Job[int]{Fn: func(int) error {
+		close(started)
+		<-gate
+		return nil
+	}}); err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	<-started
+
+	for i := 1; i <= 2; i++ {
+		h, err := p.SubmitHandle(Job[int]{Payload: i, Fn: func(n int) error {
+			mu.Lock()
+			order = append(order, n)
+			mu.Unlock()
+			return nil
+		}})
+		if err != nil {
+			t.Fatalf("submit %d: %v", i, err)
+		}
+		queued = append(queued, h)
+	}
+	ran = func() []int {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]int(nil), order...)
+	}
+	return p, queued, ran, func() { close(gate) }
+}
+
+func TestOverflowBlock(t *testing.T) {
+	p, _, _, release := fullPool(t, OverflowBlock)
+	defer p.Stop()
+	defer release()
+
+	job := Job[int]{Fn: func(int) error { return nil }}
+	if p.TrySubmit(job) {
+		t.Fatal("TrySubmit on a full queue succeeded")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := p.SubmitContext(ctx, job); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("SubmitContext err = %v; want deadline exceeded", err)
+	}
+
+	jobCtx, jobCancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, jobCancel)
+	job.Ctx = jobCtx
+	if err := p.Submit(job); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Submit err = %v; want canceled", err)
+	}
+}
+
+func TestOverflowReject(t *testing.T) {
+	p, _, ran, release := fullPool(t, OverflowReject)
+	defer p.Stop()
+
+	if err := p.Submit(Job[int]{Payload: 3, Fn: func(int) error { return nil }}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("Submit err = %v; want ErrQueueFull", err)
+	}
+	release()
+	p.Stop()
+	if got := ran(); len(got) != 2 {
+		t.Fatalf("ran %v; want the two queued jobs", got)
+	}
+}
+
+func TestOverflowDropOldest(t *testing.T) {
+	p, queued, ran, release := fullPool(t, OverflowDropOldest)
+	defer p.Stop()
+
+	h, err := p.SubmitHandle(Job[int]{Payload: 3, Fn: func(int) error { return nil }})
+	if err != nil {
+		t.Fatalf("SubmitHandle: %v", err)
+	}
+	res, _ := queued[0].Wait(context.Background())
+	if res.Status != StatusCanceled || !errors.Is(res.Err, ErrJobDropped) {
+		t.Fatalf("oldest result = %+v; want dropped", res)
+	}
+
+	release()
+	if res, _ := h.Wait(context.Background()); res.Status != StatusSucceeded {
+		t.Fatalf("newest result = %+v; want succeeded", res)
+	}
+	p.Stop()
+	if got := ran(); len(got) != 1 || got[0] != 2 {
+		t.Fatalf("ran %v; want [2]", got)
+	}
+	if s := p.Stats(); s.Dropped != 1 {
+		t.Fatalf("Dropped = %d; want 1", s.Dropped)
+	}
+}
+
+func TestOverflowDropNewest(t *testing.T) {
+	p, _, ran, release := fullPool(t, OverflowDropNewest)
+	defer p.Stop()
+
+	cleaned := make(chan struct{})
+	h, err := p.SubmitHandle(Job[int]{
+		Payload:     3,
+		Fn:          func(int) error { return nil },
+		CleanupFunc: func() { close(cleaned) },
+	})
+	if err != nil {
+		t.Fatalf("SubmitHandle: %v", err)
+	}
+	res, _ := h.Wait(context.Background())
+	if res.Status != StatusCanceled || !errors.Is(res.Err, ErrJobDropped) {
+		t.Fatalf("newest result = %+v; want dropped", res)
+	}
+	<-cleaned
+
+	release()
+	p.Stop()
+	if got := ran(); len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Fatalf("ran %v; want [1 2]", got)
+	}
+	if s := p.Stats(); s.Dropped != 1 {
+		t.Fatalf("Dropped = %d; want 1", s.Dropped)
+	}
+}
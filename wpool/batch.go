@@ -0,0 +1,255 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	lg "github.com/azargarov/go-utils/zlog"
+)
+
+const (
+	defaultBatchSize = 100
+	defaultBatchWait = 50 * time.Millisecond
+)
+
+// BatchFunc processes a batch of payloads. To fail only some items, return
+// a *BatchError; any other error fails the whole batch.
+type BatchFunc[T any] func(ctx context.Context, items []T) error
+
+// BatchConfig enables batch mode: jobs submitted without Fn are collected
+// and passed to Fn together instead of running one by one.
+type BatchConfig[T any] struct {
+	Fn BatchFunc[T]
+	// Size is the largest batch; a full batch is dispatched at once.
+	// Default 100.
+	Size int
+	// Wait is how long the first job of a batch waits for more to arrive.
+	// Default 50ms.
+	Wait time.Duration
+}
+
+// BatchError reports per-item failures of a batch: Errs[i] is the error of
+// items[i], or nil if it succeeded.
+type BatchError struct {
+	Errs []error
+}
+
+func (e *BatchError) Error() string {
+	failed, first := 0, error(nil)
+	for _, err := range e.Errs {
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			failed++
+		}
+	}
+	return fmt.Sprintf("workerpool: %d of %d batch items failed; first: %v", failed, len(e.Errs), first)
+}
+
+func (e *BatchError) Unwrap() []error {
+	var errs []error
+	for _, err := range e.Errs {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+func (c *BatchConfig[T]) normalize() {
+	if c.Size <= 0 {
+		c.Size = defaultBatchSize
+	}
+	if c.Wait <= 0 {
+		c.Wait = defaultBatchWait
+	}
+}
+
+// batched reports whether t runs through the pool's BatchFunc.
+func (t *task[T]) batched() bool { return t.job.FnCtx == nil && t.batch == nil }
+
+// collect moves batchable jobs from the head of the queue into the pending
+// batch and returns the batch once it is full, has waited long enough or
+// the pool is closing. Callers hold p.mu.
+func (p *Pool[T]) collect(now time.Time) *task[T] {
+	for t := p.queue.peek(); t != nil && t.batched() && len(p.pending) < p.batchCfg.Size; t = p.queue.peek() {
+		p.queue.take(t)
+		if t.slot {
+			p.release(t)
+			t.slot = false
+		}
+		if len(p.pending) == 0 {
+			p.pendingSince = now
+		}
+		p.pending = append(p.pending, t)
+	}
+	if len(p.pending) == 0 {
+		return nil
+	}
+	if len(p.pending) < p.batchCfg.Size && !p.isClosed && now.Sub(p.pendingSince) < p.batchCfg.Wait {
+		return nil
+	}
+	env := &task[T]{batch: p.pending, enqueued: p.pendingSince}
+	p.pending = nil
+	return env
+}
+
+// runBatch runs the jobs of one batch, retrying only the failed items in
+// further rounds until each has succeeded or exhausted its retries.
+func (p *Pool[T]) runBatch(items []*task[T]) {
+	p.activeWorkers.Add(1)
+	defer p.activeWorkers.Add(-1)
+
+	retriers := make(map[*task[T]]*retrier, len(items))
+	started := time.Now()
+	for len(items) > 0 {
+		if err := p.runCtx.Err(); err != nil {
+			for _, t := range items {
+				p.jobLog(t.job.Ctx, t).log(LogCancel, "Job skipped: pool aborted")
+				p.settle(t, Result[T]{Status: StatusCanceled, Err: err}, false)
+			}
+			return
+		}
+		live := items[:0]
+		for _, t := range items {
+			if err := t.job.Ctx.Err(); err != nil {
+				p.jobLog(t.job.Ctx, t).log(LogCancel, "Job canceled", lg.Error("reason", err))
+				p.settle(t, Result[T]{Status: StatusCanceled, Err: err}, true)
+				continue
+			}
+			if t.attempts == 0 {
+				p.running(t, started)
+				t.handle.setStatus(StatusRunning)
+				p.started(t.job.Ctx, t)
+				p.jobLog(t.job.Ctx, t).log(LogStart, "Worker processing job", lg.Int("batch", len(items)))
+			}
+			p.attempted(t, t.attempts+1)
+			live = append(live, t)
+		}
+		if len(live) == 0 {
+			return
+		}
+
+		start := time.Now()
+		errs := p.callBatch(live)
+		var retry []*task[T]
+		var wait time.Duration
+		for i, t := range live {
+			err := errs[i]
+			res := Result[T]{Err: err, Started: started}
+			logger := p.jobLog(t.job.Ctx, t)
+			if err == nil {
+				logger.log(LogSuccess, "Worker finished", lg.Int("attempt", t.attempts))
+				res.Status = StatusSucceeded
+				p.settle(t, res, true)
+				continue
+			}
+			t.tries = append(t.tries, newAttempt(start, err))
+			if p.runCtx.Err() != nil {
+				logger.log(LogCancel, "Job aborted by shutdown", lg.Int("attempt", t.attempts), lg.Error("error", err))
+				res.Status = StatusCanceled
+				p.settle(t, res, false)
+				continue
+			}
+			r, ok := retriers[t]
+			if !ok {
+				r = newRetrier(p.defaultRetry.merge(t.job.Retry))
+				retriers[t] = r
+			}
+			if _, panicked := err.(*PanicError); (panicked && p.onPanic == PanicFail) || !r.retry(t.attempts, err) {
+				logger.log(LogFailure, "Job failed", lg.Int("attempt", t.attempts), lg.Error("error", err))
+				p.deadLetter(t, t.tries, err)
+				res.Status = StatusFailed
+				p.settle(t, res, true)
+				continue
+			}
+			delay := r.delay()
+			wait = max(wait, delay)
+			logger.log(LogRetry, "Job attempt failed; backing off",
+				lg.Int("attempt", t.attempts),
+				lg.String("sleep", delay.String()),
+				lg.Error("error", err),
+			)
+			p.retrying(t.job.Ctx, t, t.attempts, err, delay)
+			t.handle.setStatus(StatusRetrying)
+			retry = append(retry, t)
+		}
+		items = retry
+		if len(items) == 0 {
+			return
+		}
+		// the next round waits for the longest backoff among its items;
+		// canceled jobs are weeded out when it starts
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-p.runCtx.Done():
+			timer.Stop()
+		}
+	}
+}
+
+// callBatch runs the BatchFunc over items and returns one error per item.
+func (p *Pool[T]) callBatch(items []*task[T]) (errs []error) {
+	payloads := make([]T, len(items))
+	for i, t := range items {
+		payloads[i] = t.job.Payload
+	}
+	fail := func(err error) []error {
+		errs := make([]error, len(items))
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			pe := &PanicError{Value: r, Stack: debug.Stack()}
+			p.logger(context.Background()).Error("Batch panicked", lg.Any("panic", r), lg.Int("batch", len(items)), lg.String("stack", string(pe.Stack)))
+			p.recovered(pe)
+			errs = fail(pe)
+		}
+	}()
+
+	ctx := p.runCtx
+	if err := p.limiter.wait(ctx); err != nil {
+		return fail(err)
+	}
+	if p.defaultRetry.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.defaultRetry.Timeout)
+		defer cancel()
+	}
+	err := p.batchCfg.Fn(ctx, payloads)
+	if err == nil {
+		return make([]error, len(items))
+	}
+	var be *BatchError
+	if errors.As(err, &be) && len(be.Errs) == len(items) {
+		return be.Errs
+	}
+	return fail(err)
+}
+
+// settle publishes the final outcome of a batched job.
+func (p *Pool[T]) settle(t *task[T], res Result[T], finished bool) {
+	res.Attempts = t.attempts
+	p.ran(t.job.Ctx, t, res)
+	if t.job.CleanupFunc != nil {
+		t.job.CleanupFunc()
+	}
+	p.attempted(t, 0)
+	p.running(t, time.Time{})
+	t.tries = nil
+	switch {
+	case t.sched != nil:
+		p.rearm(t)
+	case finished:
+		p.ack(t)
+	}
+	p.finish(t, res)
+}
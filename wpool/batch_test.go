@@ -0,0 +1,131 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type batchRecorder struct {
+	mu      sync.Mutex
+	batches [][]int
+}
+
+func (r *batchRecorder) record(items []int) {
+	r.mu.Lock()
+	r.batches = append(r.batches, append([]int(nil), items...))
+	r.mu.Unlock()
+}
+
+func (r *batchRecorder) get() [][]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([][]int(nil), r.batches...)
+}
+
+func submitAll(t *testing.T, p *Pool[int], n int) []*Handle[int] {
+	t.Helper()
+	var hs []*Handle[int]
+	for i := 1; i <= n; i++ {
+		h, err := p.SubmitHandle(Job[int]{Payload: i})
+		if err != nil {
+			t.Fatalf("submit %d: %v", i, err)
+		}
+		hs = append(hs, h)
+	}
+	return hs
+}
+
+func TestBatchFullBatches(t *testing.T) {
+	var rec batchRecorder
+	p := NewPool[int](2, fastRetry, Config[int]{Batch: BatchConfig[int]{
+		Size: 5,
+		Wait: time.Hour,
+		Fn: func(_ context.Context, items []int) error {
+			rec.record(items)
+			return nil
+		},
+	}})
+	defer p.Stop()
+
+	for _, h := range submitAll(t, p, 10) {
+		if res, _ := h.Wait(context.Background()); res.Status != StatusSucceeded {
+			t.Fatalf("result = %+v", res)
+		}
+	}
+	got := rec.get()
+	if len(got) != 2 || len(got[0]) != 5 || len(got[1]) != 5 {
+		t.Fatalf("batches = %v; want two of 5", got)
+	}
+}
+
+func TestBatchFlushesAfterWait(t *testing.T) {
+	var rec batchRecorder
+	p := NewPool[int](1, fastRetry, Config[int]{Batch: BatchConfig[int]{
+		Size: 100,
+		Wait: 20 * time.Millisecond,
+		Fn: func(_ context.Context, items []int) error {
+			rec.record(items)
+			return nil
+		},
+	}})
+	defer p.Stop()
+
+	start := time.Now()
+	hs := submitAll(t, p, 3)
+	_, _ = hs[2].Wait(context.Background())
+	if d := time.Since(start); d < 20*time.Millisecond {
+		t.Fatalf("batch flushed after %v; want >= Wait", d)
+	}
+	if got := rec.get(); len(got) != 1 || len(got[0]) != 3 {
+		t.Fatalf("batches = %v; want one of 3", got)
+	}
+}
+
+func TestBatchRetriesOnlyFailedItems(t *testing.T) {
+	var rec batchRecorder
+	dls := make(chan DeadLetter[int], 1)
+	boom := errors.New("boom")
+	p := NewPool[int](1, fastRetry, Config[int]{
+		DeadLetter: DeadLetterChan[int](dls),
+		Batch: BatchConfig[int]{
+			Size: 3,
+			Wait: time.Hour,
+			Fn: func(_ context.Context, items []int) error {
+				rec.record(items)
+				errs := make([]error, len(items))
+				for i, n := range items {
+					// 2 fails once, 3 always fails
+					if n == 3 || (n == 2 && len(rec.get()) == 1) {
+						errs[i] = boom
+					}
+				}
+				return &BatchError{Errs: errs}
+			},
+		},
+	})
+	defer p.Stop()
+
+	hs := submitAll(t, p, 3)
+	want := []Status{StatusSucceeded, StatusSucceeded, StatusFailed}
+	for i, h := range hs {
+		res, _ := h.Wait(context.Background())
+		if res.Status != want[i] {
+			t.Fatalf("item %d status = %v; want %v", i+1, res.Status, want[i])
+		}
+	}
+	got := rec.get()
+	if len(got) != 3 || len(got[0]) != 3 || len(got[1]) != 2 || len(got[2]) != 1 || got[2][0] != 3 {
+		t.Fatalf("batches = %v; want [1 2 3] [2 3] [3]", got)
+	}
+	select {
+	case dl := <-dls:
+		if dl.Payload != 3 || len(dl.Attempts) != fastRetry.Attempts || !errors.Is(dl.Err, boom) {
+			t.Fatalf("dead letter = %+v", dl)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("exhausted item was not dead-lettered")
+	}
+}
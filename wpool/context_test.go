@@ -0,0 +1,100 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAttemptTimeoutRetries(t *testing.T) {
+	ch := make(chan DeadLetter[int], 1)
+	p := NewPool[int](1, fastRetry, Config[int]{DeadLetter: DeadLetterChan[int](ch)})
+	defer p.Stop()
+
+	var attempts atomic.Int32
+	_ = p.Submit(Job[int]{
+		Retry: &RetryPolicy{Attempts: 2, Timeout: 20 * time.Millisecond},
+		FnCtx: func(ctx context.Context, _ int) error {
+			attempts.Add(1)
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	})
+
+	select {
+	case dl := <-ch:
+		if !errors.Is(dl.Err, context.DeadlineExceeded) {
+			t.Fatalf("final error = %v; want deadline exceeded", dl.Err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out job was not dead-lettered")
+	}
+	if got := attempts.Load(); got != 2 {
+		t.Fatalf("attempts = %d; want 2", got)
+	}
+}
+
+func TestJobCtxCancelInterruptsAttempt(t *testing.T) {
+	p := NewPool[int](1, fastRetry)
+	defer p.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var attempts atomic.Int32
+	started := make(chan struct{})
+	finished := make(chan struct{})
+	_ = p.Submit(Job[int]{
+		Ctx: ctx,
+		FnCtx: func(ctx context.Context, _ int) error {
+			attempts.Add(1)
+			close(started)
+			<-ctx.Done()
+			close(finished)
+			return ctx.Err()
+		},
+	})
+
+	<-started
+	cancel()
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("attempt did not observe Job.Ctx cancellation")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if got := attempts.Load(); got != 1 {
+		t.Fatalf("attempts = %d; want 1 (no retry after cancel)", got)
+	}
+}
+
+func TestShutdownGraceCancelsInFlight(t *testing.T) {
+	p := NewPool[int](1, fastRetry, Config[int]{ShutdownGrace: 20 * time.Millisecond})
+
+	started := make(chan struct{})
+	var sawCancel atomic.Bool
+	_ = p.Submit(Job[int]{FnCtx: func(ctx context.Context, _ int) error {
+		close(started)
+		select {
+		case <-ctx.Done():
+			sawCancel.Store(true)
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+			return nil
+		}
+	}})
+	<-started
+
+	begin := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := p.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if !sawCancel.Load() {
+		t.Fatal("in-flight attempt was not canceled after the grace period")
+	}
+	if d := time.Since(begin); d > 500*time.Millisecond {
+		t.Fatalf("Shutdown took %v; want about the grace period", d)
+	}
+}
@@ -0,0 +1,123 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrParentFailed is the Result error of a DAG job skipped because one of
+// its parents did not succeed.
+var ErrParentFailed = errors.New("workerpool: parent job did not succeed")
+
+// DAG runs named jobs on a pool, submitting each one only after all of its
+// parents have succeeded. Parents must be added before their children, which
+// also rules out cycles. A DAG is not safe for concurrent Add and Run.
+type DAG[T any] struct {
+	pool  *Pool[T]
+	nodes []*dagNode[T] // in Add order
+	index map[string]*dagNode[T]
+}
+
+type dagNode[T any] struct {
+	name     string
+	job      Job[T]
+	parents  int
+	children []*dagNode[T]
+}
+
+// NewDAG returns an empty DAG whose jobs run on p.
+func NewDAG[T any](p *Pool[T]) *DAG[T] {
+	return &DAG[T]{pool: p, index: make(map[string]*dagNode[T])}
+}
+
+// Add registers job under name, to run once every job in parents succeeded.
+func (d *DAG[T]) Add(name string, job Job[T], parents ...string) error {
+	if _, ok := d.index[name]; ok {
+		return fmt.Errorf("workerpool: dag job %q already added", name)
+	}
+	for _, parent := range parents {
+		if _, ok := d.index[parent]; !ok {
+			return fmt.Errorf("workerpool: dag job %q: unknown parent %q", name, parent)
+		}
+	}
+	n := &dagNode[T]{name: name, job: job, parents: len(parents)}
+	for _, parent := range parents {
+		pn := d.index[parent]
+		pn.children = append(pn.children, n)
+	}
+	d.nodes = append(d.nodes, n)
+	d.index[name] = n
+	return nil
+}
+
+type dagDone[T any] struct {
+	node *dagNode[T]
+	res  Result[T]
+}
+
+// Run submits the DAG's jobs as their parents succeed and waits until every
+// job has finished or been skipped. Jobs without their own Ctx run with ctx.
+// The returned map holds each job's Result by name; skipped jobs complete as
+// StatusCanceled with ErrParentFailed. The error reports the first job, in
+// Add order, that failed or could not be submitted.
+func (d *DAG[T]) Run(ctx context.Context) (map[string]Result[T], error) {
+	results := make(map[string]Result[T], len(d.nodes))
+	waiting := make(map[*dagNode[T]]int, len(d.nodes))
+	done := make(chan dagDone[T], len(d.nodes))
+	running := 0
+
+	var settle func(n *dagNode[T], res Result[T])
+	submit := func(n *dagNode[T]) {
+		job := n.job
+		if job.Ctx == nil {
+			job.Ctx = ctx
+		}
+		h, err := d.pool.SubmitHandle(job)
+		if err != nil {
+			settle(n, Result[T]{Payload: job.Payload, Status: StatusCanceled, Err: err})
+			return
+		}
+		running++
+		go func() {
+			<-h.Done()
+			res, _ := h.Result()
+			done <- dagDone[T]{node: n, res: res}
+		}()
+	}
+	settle = func(n *dagNode[T], res Result[T]) {
+		results[n.name] = res
+		for _, c := range n.children {
+			if _, ok := results[c.name]; ok {
+				continue // already skipped through another parent
+			}
+			if res.Status != StatusSucceeded {
+				settle(c, Result[T]{Payload: c.job.Payload, Status: StatusCanceled, Err: ErrParentFailed})
+				continue
+			}
+			waiting[c]++
+			if waiting[c] == c.parents {
+				submit(c)
+			}
+		}
+	}
+
+	for _, n := range d.nodes {
+		if n.parents == 0 {
+			submit(n)
+		}
+	}
+	for running > 0 {
+		r := <-done
+		running--
+		settle(r.node, r.res)
+	}
+
+	for _, n := range d.nodes {
+		res := results[n.name]
+		if res.Status != StatusSucceeded && !errors.Is(res.Err, ErrParentFailed) {
+			return results, fmt.Errorf("workerpool: dag job %q: %w", n.name, res.Err)
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,112 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestDAGOrder(t *testing.T) {
+	p := NewPool[string](4, fastRetry)
+	defer p.Stop()
+
+	var mu sync.Mutex
+	var order []string
+	job := func(name string) Job[string] {
+		return Job[string]{Payload: name, Fn: func(s string) error {
+			mu.Lock()
+			order = append(order, s)
+			mu.Unlock()
+			return nil
+		}}
+	}
+	d := NewDAG(p)
+	for _, err := range []error{
+		d.Add("fetch", job("fetch")),
+		d.Add("parse", job("parse"), "fetch"),
+		d.Add("thumbs", job("thumbs"), "fetch"),
+		d.Add("index", job("index"), "parse", "thumbs"),
+	} {
+		if err != nil {
+			t.Fatalf("add: %v", err)
+		}
+	}
+	if err := d.Add("index", job("index")); err == nil {
+		t.Fatal("duplicate name accepted")
+	}
+	if err := d.Add("x", job("x"), "missing"); err == nil {
+		t.Fatal("unknown parent accepted")
+	}
+
+	results, err := d.Run(context.Background())
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	pos := make(map[string]int)
+	for i, s := range order {
+		pos[s] = i
+	}
+	if len(order) != 4 || pos["fetch"] != 0 || pos["index"] != 3 {
+		t.Fatalf("order %v; want fetch first and index last", order)
+	}
+	if results["index"].Status != StatusSucceeded {
+		t.Fatalf("index result = %+v", results["index"])
+	}
+}
+
+func TestDAGSkipsDescendantsOfFailed(t *testing.T) {
+	p := NewPool[string](2, fastRetry)
+	defer p.Stop()
+
+	boom := errors.New("boom")
+	var ran sync.Map
+	ok := func(s string) error { ran.Store(s, true); return nil }
+	d := NewDAG(p)
+	_ = d.Add("a", Job[string]{Payload: "a", Fn: ok})
+	_ = d.Add("b", Job[string]{Payload: "b", Fn: func(string) error { return boom },
+		Retry: &RetryPolicy{Attempts: 1}})
+	_ = d.Add("c", Job[string]{Payload: "c", Fn: ok}, "a", "b")
+	_ = d.Add("d", Job[string]{Payload: "d", Fn: ok}, "c")
+	_ = d.Add("e", Job[string]{Payload: "e", Fn: ok}, "a")
+
+	results, err := d.Run(context.Background())
+	if !errors.Is(err, boom) {
+		t.Fatalf("run err = %v; want boom", err)
+	}
+	if results["b"].Status != StatusFailed {
+		t.Fatalf("b result = %+v", results["b"])
+	}
+	for _, name := range []string{"c", "d"} {
+		if res := results[name]; res.Status != StatusCanceled || !errors.Is(res.Err, ErrParentFailed) {
+			t.Fatalf("%s result = %+v; want skipped", name, res)
+		}
+		if _, ok := ran.Load(name); ok {
+			t.Fatalf("%s ran after its parent failed", name)
+		}
+	}
+	if results["e"].Status != StatusSucceeded {
+		t.Fatalf("e result = %+v", results["e"])
+	}
+}
+
+func TestDAGRejectedAddLeavesNoOrphan(t *testing.T) {
+	p := NewPool[string](2, fastRetry)
+	defer p.Stop()
+
+	boom := errors.New("boom")
+	d := NewDAG(p)
+	_ = d.Add("a", Job[string]{Payload: "a", Fn: func(string) error { return boom },
+		Retry: &RetryPolicy{Attempts: 1}})
+	if err := d.Add("c", Job[string]{Payload: "c", Fn: func(string) error { return nil }}, "a", "missing"); err == nil {
+		t.Fatal("unknown parent accepted")
+	}
+
+	results, err := d.Run(context.Background())
+	if !errors.Is(err, boom) {
+		t.Fatalf("run err = %v; want boom", err)
+	}
+	if _, ok := results["c"]; ok || len(results) != 1 {
+		t.Fatalf("results = %+v; want only a", results)
+	}
+}
@@ -0,0 +1,150 @@
+package workerpool
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	lg "github.com/azargarov/go-utils/zlog"
+)
+
+var ErrDeadLetterFull = errors.New("workerpool: dead-letter channel full")
+
+// Attempt records the outcome of one failed execution of a job.
+type Attempt struct {
+	Start time.Time
+	End   time.Time
+	Error string
+}
+
+func newAttempt(start time.Time, err error) Attempt {
+	return Attempt{Start: start, End: time.Now(), Error: err.Error()}
+}
+
+// DeadLetter describes a job that exhausted its retries.
+type DeadLetter[T any] struct {
+	// ID is assigned by the DeadLetterStore holding the entry.
+	ID        uint64
+	Payload   T
+	Priority  int
+	Tenant    string
+	Submitted time.Time
+	FailedAt  time.Time
+	Attempts  []Attempt // one entry per failed attempt, oldest first
+	// Err is the error of the final attempt. It is not persisted.
+	Err error `json:"-"`
+	// Fn is the job's function, used by Requeue. It is not persisted;
+	// requeued entries without Fn run with the pool's Handler.
+	Fn JobFuncCtx[T] `json:"-"`
+}
+
+// DeadLetterSink receives jobs whose final attempt failed. Add is called on
+// the worker goroutine, after the last attempt and before the job's cleanup.
+type DeadLetterSink[T any] interface {
+	Add(dl DeadLetter[T]) error
+}
+
+// DeadLetterFunc adapts a function to a DeadLetterSink.
+type DeadLetterFunc[T any] func(dl DeadLetter[T])
+
+func (f DeadLetterFunc[T]) Add(dl DeadLetter[T]) error {
+	f(dl)
+	return nil
+}
+
+// DeadLetterChan delivers dead letters on a channel. It never blocks a
+// worker: when the channel is full, Add fails with ErrDeadLetterFull.
+type DeadLetterChan[T any] chan<- DeadLetter[T]
+
+func (c DeadLetterChan[T]) Add(dl DeadLetter[T]) error {
+	select {
+	case c <- dl:
+		return nil
+	default:
+		return ErrDeadLetterFull
+	}
+}
+
+// DeadLetterStore keeps dead letters for inspection and re-enqueueing. It is
+// backed by a Queue, so a FileQueue[DeadLetter[T]] makes it persistent.
+type DeadLetterStore[T any] struct {
+	q Queue[DeadLetter[T]]
+}
+
+// Ensure DeadLetterStore satisfies DeadLetterSink at compile time.
+var _ DeadLetterSink[int] = (*DeadLetterStore[int])(nil)
+
+// NewDeadLetterStore returns a store over q; a nil q keeps entries in memory.
+func NewDeadLetterStore[T any](q Queue[DeadLetter[T]]) *DeadLetterStore[T] {
+	if q == nil {
+		q = NewMemoryQueue[DeadLetter[T]]()
+	}
+	return &DeadLetterStore[T]{q: q}
+}
+
+func (s *DeadLetterStore[T]) Add(dl DeadLetter[T]) error {
+	_, err := s.q.Put(Record[DeadLetter[T]]{Payload: dl})
+	return err
+}
+
+// List returns the stored dead letters, oldest first.
+func (s *DeadLetterStore[T]) List() ([]DeadLetter[T], error) {
+	recs, err := s.q.Pending()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]DeadLetter[T], len(recs))
+	for i, r := range recs {
+		out[i] = r.Payload
+		out[i].ID = r.ID
+	}
+	return out, nil
+}
+
+// Remove deletes the dead letter with the given ID.
+func (s *DeadLetterStore[T]) Remove(id uint64) error { return s.q.Ack(id) }
+
+// Requeue submits the dead letter with the given ID to p as a fresh job and
+// removes it from the store.
+func (s *DeadLetterStore[T]) Requeue(p *Pool[T], id uint64) error {
+	list, err := s.List()
+	if err != nil {
+		return err
+	}
+	for _, dl := range list {
+		if dl.ID == id {
+			if err := p.Requeue(dl); err != nil {
+				return err
+			}
+			return s.Remove(id)
+		}
+	}
+	return fmt.Errorf("workerpool: dead letter %d not found", id)
+}
+
+// Requeue submits a dead-lettered job again with a fresh retry budget.
+func (p *Pool[T]) Requeue(dl DeadLetter[T]) error {
+	return p.Submit(Job[T]{Payload: dl.Payload, FnCtx: dl.Fn, Priority: dl.Priority, Tenant: dl.Tenant})
+}
+
+// deadLetter hands a job that exhausted its retries to the configured sink.
+func (p *Pool[T]) deadLetter(t *task[T], attempts []Attempt, err error) {
+	if p.deadLetters == nil {
+		return
+	}
+	dl := DeadLetter[T]{
+		Payload:   t.job.Payload,
+		Priority:  t.job.Priority,
+		Tenant:    t.job.Tenant,
+		Submitted: t.enqueued,
+		FailedAt:  time.Now(),
+		Attempts:  attempts,
+		Err:       err,
+		Fn:        t.job.FnCtx,
+	}
+	if err := p.deadLetters.Add(dl); err != nil {
+		p.logger(t.job.Ctx).Error("Dead-letter sink failed", lg.Any("job_id", t.id), lg.Error("error", err))
+		return
+	}
+	p.metrics.deadLetters.Add(1)
+}
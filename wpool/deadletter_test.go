@@ -0,0 +1,98 @@
+package workerpool
+
+import (
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDeadLetterAfterFinalAttempt(t *testing.T) {
+	ch := make(chan DeadLetter[int], 1)
+	p := NewPool[int](1, fastRetry, Config[int]{DeadLetter: DeadLetterChan[int](ch)})
+	defer p.Stop()
+
+	boom := errors.New("boom")
+	_ = p.Submit(Job[int]{Payload: 7, Priority: 2, Fn: func(int) error { return boom }})
+
+	select {
+	case dl := <-ch:
+		if dl.Payload != 7 || dl.Priority != 2 || !errors.Is(dl.Err, boom) {
+			t.Fatalf("dead letter = %+v", dl)
+		}
+		if len(dl.Attempts) != fastRetry.Attempts {
+			t.Fatalf("attempts = %d; want %d", len(dl.Attempts), fastRetry.Attempts)
+		}
+		for _, a := range dl.Attempts {
+			if a.Error != "boom" || a.End.Before(a.Start) || a.Start.Before(dl.Submitted) {
+				t.Fatalf("bad attempt record %+v", a)
+			}
+		}
+	case <-time.After(time.Second):
+		t.Fatal("job was not dead-lettered")
+	}
+}
+
+func TestDeadLetterStoreRequeue(t *testing.T) {
+	store := NewDeadLetterStore[int](nil)
+	p := NewPool[int](1, fastRetry, Config[int]{DeadLetter: store})
+	defer p.Stop()
+
+	var calls atomic.Int32
+	done := make(chan struct{})
+	_ = p.Submit(Job[int]{Payload: 1, Retry: &RetryPolicy{Attempts: 1}, Fn: func(int) error {
+		if calls.Add(1) == 1 {
+			return errors.New("downstream unavailable")
+		}
+		close(done)
+		return nil
+	}})
+
+	var list []DeadLetter[int]
+	deadline := time.After(time.Second)
+	for len(list) == 0 {
+		select {
+		case <-deadline:
+			t.Fatal("job was not dead-lettered")
+		case <-time.After(5 * time.Millisecond):
+		}
+		list, _ = store.List()
+	}
+
+	if err := store.Requeue(p, list[0].ID); err != nil {
+		t.Fatalf("Requeue: %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("requeued job did not run")
+	}
+	if list, _ := store.List(); len(list) != 0 {
+		t.Fatalf("store still holds %d entries after requeue", len(list))
+	}
+	if err := store.Requeue(p, 999); err == nil {
+		t.Fatal("Requeue of unknown id succeeded; want error")
+	}
+}
+
+func TestDeadLetterStorePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead.log")
+	q, err := OpenFileQueue[DeadLetter[string]](path, nil)
+	if err != nil {
+		t.Fatalf("OpenFileQueue: %v", err)
+	}
+	store := NewDeadLetterStore[string](q)
+	_ = store.Add(DeadLetter[string]{Payload: "order-42", Attempts: []Attempt{{Error: "timeout"}}})
+	_ = q.Close()
+
+	q, _ = OpenFileQueue[DeadLetter[string]](path, nil)
+	defer q.Close()
+	list, err := NewDeadLetterStore[string](q).List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(list) != 1 || list[0].Payload != "order-42" || list[0].Attempts[0].Error != "timeout" {
+		t.Fatalf("restored %+v", list)
+	}
+}
@@ -0,0 +1,75 @@
+package workerpool
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrDuplicate is returned when a job's Key matches a queued, running or
+// recently completed job and the pool rejects duplicates.
+var ErrDuplicate = errors.New("workerpool: duplicate job")
+
+// DedupMode selects what happens to a job whose Key is already known.
+type DedupMode int
+
+const (
+	// DedupReject fails the submission with ErrDuplicate.
+	DedupReject DedupMode = iota
+	// DedupCoalesce accepts the submission without queuing it again:
+	// SubmitHandle returns the Handle of the original job.
+	DedupCoalesce
+)
+
+// keyEntry tracks the job currently holding a dedup key.
+type keyEntry[T any] struct {
+	handle  *Handle[T]
+	expires time.Time // zero while the job is queued or running
+}
+
+type seenKey struct {
+	key     string
+	expires time.Time
+}
+
+// claimKey reserves key for h. If the key is taken it returns the
+// holder's handle. Callers hold p.mu.
+func (p *Pool[T]) claimKey(key string, h *Handle[T]) (*Handle[T], bool) {
+	p.expireKeys(time.Now())
+	if e, ok := p.keys[key]; ok {
+		return e.handle, false
+	}
+	p.keys[key] = &keyEntry[T]{handle: h}
+	return nil, true
+}
+
+// releaseKey frees the key of a finished job. Succeeded jobs keep it for
+// DedupTTL so late redeliveries are still caught; failed and canceled ones
+// free it at once so a redelivery can try again.
+func (p *Pool[T]) releaseKey(key string, h *Handle[T], status Status) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e, ok := p.keys[key]
+	if !ok || e.handle != h {
+		return
+	}
+	if status != StatusSucceeded || p.dedupTTL <= 0 {
+		delete(p.keys, key)
+		return
+	}
+	e.expires = time.Now().Add(p.dedupTTL)
+	p.seen = append(p.seen, seenKey{key: key, expires: e.expires})
+}
+
+// expireKeys forgets completed keys whose TTL has passed. Since the TTL is
+// fixed, p.seen is ordered by expiry. Callers hold p.mu.
+func (p *Pool[T]) expireKeys(now time.Time) {
+	n := 0
+	for ; n < len(p.seen) && !p.seen[n].expires.After(now); n++ {
+		if e, ok := p.keys[p.seen[n].key]; ok && e.expires.Equal(p.seen[n].expires) {
+			delete(p.keys, p.seen[n].key)
+		}
+	}
+	if n > 0 {
+		p.seen = append(p.seen[:0], p.seen[n:]...)
+	}
+}
@@ -0,0 +1,82 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDedupRejectsInFlight(t *testing.T) {
+	p := NewPool[int](1, fastRetry)
+	defer p.Stop()
+
+	release := make(chan struct{})
+	var runs atomic.Int32
+	fn := func(int) error {
+		runs.Add(1)
+		<-release
+		return nil
+	}
+	h, err := p.SubmitHandle(Job[int]{Key: "evt-1", Fn: fn})
+	if err != nil {
+		t.Fatalf("first submit: %v", err)
+	}
+	if err := p.Submit(Job[int]{Key: "evt-1", Fn: fn}); !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("duplicate submit err = %v; want ErrDuplicate", err)
+	}
+	if err := p.Submit(Job[int]{Key: "evt-2", Fn: func(int) error { return nil }}); err != nil {
+		t.Fatalf("other key: %v", err)
+	}
+	close(release)
+	_, _ = h.Wait(context.Background())
+
+	// without a TTL the key is free again once the job is done
+	if err := p.Submit(Job[int]{Key: "evt-1", Fn: fn}); err != nil {
+		t.Fatalf("resubmit after completion: %v", err)
+	}
+}
+
+func TestDedupCoalesceAndTTL(t *testing.T) {
+	p := NewPool[int](1, fastRetry, Config[int]{Dedup: DedupCoalesce, DedupTTL: 50 * time.Millisecond})
+	defer p.Stop()
+
+	var runs atomic.Int32
+	fn := func(int) error {
+		runs.Add(1)
+		return nil
+	}
+	h1, _ := p.SubmitHandle(Job[int]{Key: "k", Fn: fn})
+	h2, err := p.SubmitHandle(Job[int]{Key: "k", Fn: fn})
+	if err != nil || h1 != h2 {
+		t.Fatalf("coalesced submit = %p, %v; want the original handle", h2, err)
+	}
+	_, _ = h1.Wait(context.Background())
+
+	// within the TTL a redelivery still maps to the finished job
+	h3, _ := p.SubmitHandle(Job[int]{Key: "k", Fn: fn})
+	if h3 != h1 {
+		t.Fatal("redelivery within TTL was not coalesced")
+	}
+	time.Sleep(60 * time.Millisecond)
+	h4, _ := p.SubmitHandle(Job[int]{Key: "k", Fn: fn})
+	if h4 == h1 {
+		t.Fatal("key still remembered after TTL")
+	}
+	_, _ = h4.Wait(context.Background())
+	if got := runs.Load(); got != 2 {
+		t.Fatalf("runs = %d; want 2", got)
+	}
+}
+
+func TestDedupFailedJobFreesKey(t *testing.T) {
+	p := NewPool[int](1, fastRetry, Config[int]{DedupTTL: time.Hour})
+	defer p.Stop()
+
+	h, _ := p.SubmitHandle(Job[int]{Key: "k", Retry: &RetryPolicy{Attempts: 1}, Fn: func(int) error { return errors.New("boom") }})
+	_, _ = h.Wait(context.Background())
+	if err := p.Submit(Job[int]{Key: "k", Fn: func(int) error { return nil }}); err != nil {
+		t.Fatalf("resubmit after failure: %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// MapOptions configures Map and ForEach.
+type MapOptions struct {
+	// Workers is the number of items processed at once. Default
+	// DefaultMaxWorkers, capped at the number of items.
+	Workers int
+	// Retry applies to every item; zero fields take the pool defaults.
+	Retry RetryPolicy
+	// StopOnError cancels the remaining items once one has failed its
+	// final attempt: queued items are skipped and running ones see their
+	// context canceled.
+	StopOnError bool
+}
+
+// MapError reports per-item failures of Map or ForEach: Errs[i] is the error
+// of items[i], or nil if it succeeded.
+type MapError struct {
+	Errs []error
+}
+
+// Error reports the number of failed items and the first failure that is
+// not a cancellation, since with StopOnError those follow from it.
+func (e *MapError) Error() string {
+	failed, first := 0, -1
+	for i, err := range e.Errs {
+		if err == nil {
+			continue
+		}
+		failed++
+		if first < 0 || (errors.Is(e.Errs[first], context.Canceled) && !errors.Is(err, context.Canceled)) {
+			first = i
+		}
+	}
+	return fmt.Sprintf("workerpool: %d of %d items failed; item %d: %v", failed, len(e.Errs), first, e.Errs[max(first, 0)])
+}
+
+func (e *MapError) Unwrap() []error {
+	var errs []error
+	for _, err := range e.Errs {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// Map applies fn to every item on a temporary pool and returns the outputs in
+// input order. If any item fails, the error is a *MapError and the outputs of
+// failed items are zero values. Canceling ctx cancels the remaining items.
+func Map[In, Out any](ctx context.Context, items []In, fn func(ctx context.Context, in In) (Out, error), opts ...MapOptions) ([]Out, error) {
+	var o MapOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	out := make([]Out, len(items))
+	if len(items) == 0 {
+		return out, nil
+	}
+	if o.Workers <= 0 {
+		o.Workers = DefaultMaxWorkers
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var cfg Config[int]
+	if o.StopOnError {
+		cfg.Hooks.OnFailure = func(context.Context, Event[int]) { cancel() }
+	}
+	p := NewPool[int](min(o.Workers, len(items)), o.Retry, cfg)
+
+	errs := make([]error, len(items))
+	handles := make([]*Handle[int], len(items))
+	for i := range items {
+		h, err := p.SubmitHandle(Job[int]{
+			Payload: i,
+			Ctx:     ctx,
+			FnCtx: func(ctx context.Context, i int) error {
+				v, err := fn(ctx, items[i])
+				if err != nil {
+					return err
+				}
+				out[i] = v
+				return nil
+			},
+		})
+		handles[i], errs[i] = h, err
+	}
+	p.Stop()
+
+	failed := false
+	for i, h := range handles {
+		if h != nil {
+			if res, _ := h.Result(); res.Status != StatusSucceeded {
+				errs[i] = res.Err
+			}
+		}
+		failed = failed || errs[i] != nil
+	}
+	if failed {
+		return out, &MapError{Errs: errs}
+	}
+	return out, nil
+}
+
+// ForEach calls fn for every item on a temporary pool, like Map without
+// outputs.
+func ForEach[In any](ctx context.Context, items []In, fn func(ctx context.Context, in In) error, opts ...MapOptions) error {
+	_, err := Map(ctx, items, func(ctx context.Context, in In) (struct{}, error) {
+		return struct{}{}, fn(ctx, in)
+	}, opts...)
+	return err
+}
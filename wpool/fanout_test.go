@@ -0,0 +1,94 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMapPreservesOrder(t *testing.T) {
+	items := make([]int, 50)
+	for i := range items {
+		items[i] = i
+	}
+	var flaky atomic.Bool
+	out, err := Map(context.Background(), items, func(_ context.Context, n int) (int, error) {
+		time.Sleep(time.Duration(50-n) * 100 * time.Microsecond) // finish out of order
+		if n == 7 && !flaky.Swap(true) {
+			return 0, errors.New("flaky")
+		}
+		return n * n, nil
+	}, MapOptions{Workers: 8, Retry: fastRetry})
+	if err != nil {
+		t.Fatalf("Map: %v", err)
+	}
+	for i, v := range out {
+		if v != i*i {
+			t.Fatalf("out[%d] = %d; want %d", i, v, i*i)
+		}
+	}
+}
+
+func TestMapAggregatesErrors(t *testing.T) {
+	errOdd := errors.New("odd")
+	out, err := Map(context.Background(), []int{0, 1, 2, 3}, func(_ context.Context, n int) (string, error) {
+		if n%2 == 1 {
+			return "", errOdd
+		}
+		return "ok", nil
+	}, MapOptions{Retry: RetryPolicy{Attempts: 1}})
+
+	var me *MapError
+	if !errors.As(err, &me) || !errors.Is(err, errOdd) {
+		t.Fatalf("err = %v; want *MapError wrapping errOdd", err)
+	}
+	for i, e := range me.Errs {
+		if (e != nil) != (i%2 == 1) {
+			t.Fatalf("Errs = %v", me.Errs)
+		}
+	}
+	if out[0] != "ok" || out[1] != "" || out[2] != "ok" {
+		t.Fatalf("out = %q", out)
+	}
+	if !strings.Contains(err.Error(), "2 of 4 items failed; item 1: odd") {
+		t.Fatalf("message = %q", err)
+	}
+}
+
+func TestForEachStopOnError(t *testing.T) {
+	items := make([]int, 100)
+	for i := range items {
+		items[i] = i
+	}
+	boom := errors.New("boom")
+	var calls atomic.Int32
+	err := ForEach(context.Background(), items, func(ctx context.Context, n int) error {
+		calls.Add(1)
+		if n == 3 {
+			return boom
+		}
+		select {
+		case <-time.After(5 * time.Millisecond):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}, MapOptions{Workers: 2, Retry: RetryPolicy{Attempts: 1}, StopOnError: true})
+
+	if !errors.Is(err, boom) || !strings.Contains(err.Error(), "item 3: boom") {
+		t.Fatalf("err = %v; want item 3's boom first", err)
+	}
+	if n := calls.Load(); n > 20 {
+		t.Fatalf("%d items ran after the failure; want the rest skipped", n)
+	}
+}
+
+func TestMapEmpty(t *testing.T) {
+	out, err := Map(context.Background(), nil, func(context.Context, int) (int, error) { return 0, nil })
+	if err != nil || len(out) != 0 {
+		t.Fatalf("Map(nil) = %v, %v", out, err)
+	}
+}
@@ -0,0 +1,375 @@
+package workerpool
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	opPut       byte = 1
+	opAck       byte = 2
+	opPutTenant byte = 3 // opPut followed by a tenant, written only for a non-empty one
+	opPutKeys   byte = 4 // opPut followed by tenant, Key and SerialKey, written if either key is set
+
+	entryHeaderSize     = 8             // body length + crc32
+	putBodyPrefix       = 1 + 8 + 8 + 8 // op, id, priority, run-at
+	strLenSize          = 2             // length prefix of the strings of opPutTenant and opPutKeys
+	maxStrLen           = 1<<16 - 1
+	defaultCompactAfter = 1024
+	maxEntrySize        = 64 << 20
+)
+
+var ErrQueueClosed = errors.New("workerpool: queue closed")
+
+// FileQueueConfig holds optional FileQueue settings.
+type FileQueueConfig struct {
+	// NoSync skips the fsync after every Put. Faster, but jobs accepted just
+	// before an OS crash or power loss may be lost.
+	NoSync bool
+	// CompactAfter rewrites the log once this many acknowledged records have
+	// accumulated and they outnumber the pending ones. Default 1024.
+	CompactAfter int
+}
+
+// FileQueue is a crash-safe Queue backed by an append-only log file.
+//
+// Every Put and Ack appends a checksummed entry. On open the log is replayed;
+// a torn entry at the tail (from a crash mid-write) is truncated away. The log
+// is compacted in place once enough records are acknowledged.
+type FileQueue[T any] struct {
+	mu      sync.Mutex
+	path    string
+	f       *os.File
+	codec   Codec[T]
+	cfg     FileQueueConfig
+	nextID  uint64
+	pending map[uint64][]byte // id -> encoded put entry, kept for compaction
+	acked   int               // ack entries in the log since the last compaction
+	end     int64             // size of the log up to its last complete entry
+}
+
+// Ensure FileQueue satisfies Queue at compile time.
+var _ Queue[int] = (*FileQueue[int])(nil)
+
+// OpenFileQueue opens or creates the log at path and loads its pending records.
+// A nil codec defaults to JSONCodec.
+func OpenFileQueue[T any](path string, codec Codec[T], config ...FileQueueConfig) (*FileQueue[T], error) {
+	if codec == nil {
+		codec = JSONCodec[T]{}
+	}
+	var cfg FileQueueConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.CompactAfter <= 0 {
+		cfg.CompactAfter = defaultCompactAfter
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("workerpool: file queue: %w", err)
+	}
+	q := &FileQueue[T]{
+		path:    path,
+		f:       f,
+		codec:   codec,
+		cfg:     cfg,
+		pending: make(map[uint64][]byte),
+	}
+	if err := q.load(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return q, nil
+}
+
+// load replays the log and truncates a torn tail entry, if any.
+func (q *FileQueue[T]) load() error {
+	r := bufio.NewReader(q.f)
+	var good int64
+	for {
+		entry, body, err := readEntry(r)
+		if err != nil {
+			break // EOF or a torn/corrupt tail: keep everything before it
+		}
+		if len(body) < 9 {
+			break
+		}
+		id := binary.BigEndian.Uint64(body[1:9])
+		switch body[0] {
+		case opPut, opPutTenant, opPutKeys:
+			q.pending[id] = entry
+		case opAck:
+			delete(q.pending, id)
+			q.acked++
+		default:
+			return fmt.Errorf("workerpool: file queue: unknown op %d at offset %d", body[0], good)
+		}
+		if id >= q.nextID {
+			q.nextID = id
+		}
+		good += int64(len(entry))
+	}
+	if err := q.f.Truncate(good); err != nil {
+		return fmt.Errorf("workerpool: file queue: %w", err)
+	}
+	if _, err := q.f.Seek(good, io.SeekStart); err != nil {
+		return fmt.Errorf("workerpool: file queue: %w", err)
+	}
+	q.end = good
+	return nil
+}
+
+// readEntry returns the whole entry and its body, verifying the checksum.
+func readEntry(r io.Reader) (entry, body []byte, err error) {
+	var hdr [entryHeaderSize]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, nil, err
+	}
+	n := binary.BigEndian.Uint32(hdr[0:4])
+	if n > maxEntrySize {
+		return nil, nil, errors.New("entry too large")
+	}
+	entry = make([]byte, entryHeaderSize+int(n))
+	copy(entry, hdr[:])
+	if _, err := io.ReadFull(r, entry[entryHeaderSize:]); err != nil {
+		return nil, nil, err
+	}
+	body = entry[entryHeaderSize:]
+	if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(hdr[4:8]) {
+		return nil, nil, errors.New("checksum mismatch")
+	}
+	return entry, body, nil
+}
+
+func frame(body []byte) []byte {
+	entry := make([]byte, entryHeaderSize+len(body))
+	binary.BigEndian.PutUint32(entry[0:4], uint32(len(body)))
+	binary.BigEndian.PutUint32(entry[4:8], crc32.ChecksumIEEE(body))
+	copy(entry[entryHeaderSize:], body)
+	return entry
+}
+
+func (q *FileQueue[T]) Put(r Record[T]) (uint64, error) {
+	data, err := q.codec.Marshal(r.Payload)
+	if err != nil {
+		return 0, fmt.Errorf("workerpool: file queue: encode payload: %w", err)
+	}
+	op, strs := opPut, []string(nil)
+	switch {
+	case r.Key != "" || r.SerialKey != "":
+		op, strs = opPutKeys, []string{r.Tenant, r.Key, r.SerialKey}
+	case r.Tenant != "":
+		op, strs = opPutTenant, []string{r.Tenant}
+	}
+	size := putBodyPrefix + len(data)
+	for _, str := range strs {
+		if len(str) > maxStrLen {
+			return 0, errors.New("workerpool: file queue: tenant or key too long")
+		}
+		size += strLenSize + len(str)
+	}
+	if size > maxEntrySize {
+		return 0, fmt.Errorf("workerpool: file queue: record of %d bytes exceeds the %d byte limit", size, maxEntrySize)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.f == nil {
+		return 0, ErrQueueClosed
+	}
+	id := q.nextID + 1
+	body := make([]byte, putBodyPrefix, size)
+	body[0] = op
+	binary.BigEndian.PutUint64(body[1:9], id)
+	binary.BigEndian.PutUint64(body[9:17], uint64(int64(r.Priority)))
+	var runAt int64
+	if !r.RunAt.IsZero() {
+		runAt = r.RunAt.UnixNano()
+	}
+	binary.BigEndian.PutUint64(body[17:25], uint64(runAt))
+	for _, str := range strs {
+		body = binary.BigEndian.AppendUint16(body, uint16(len(str)))
+		body = append(body, str...)
+	}
+	body = append(body, data...)
+
+	entry := frame(body)
+	if err := q.write(entry, !q.cfg.NoSync); err != nil {
+		return 0, err
+	}
+	q.nextID = id
+	q.pending[id] = entry
+	return id, nil
+}
+
+func (q *FileQueue[T]) Ack(id uint64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.f == nil {
+		return ErrQueueClosed
+	}
+	if _, ok := q.pending[id]; !ok {
+		return nil
+	}
+	body := make([]byte, 9)
+	body[0] = opAck
+	binary.BigEndian.PutUint64(body[1:9], id)
+	if err := q.write(frame(body), false); err != nil {
+		return err
+	}
+	delete(q.pending, id)
+	q.acked++
+	if q.acked >= q.cfg.CompactAfter && q.acked > len(q.pending) {
+		return q.compact()
+	}
+	return nil
+}
+
+// write appends entry to the log and, if sync is set, flushes it to disk.
+// On failure the log is cut back to its last complete entry, so a partial
+// entry or one reported as failed is not replayed on the next open. If even
+// that fails the file is closed and later calls return ErrQueueClosed.
+// Callers hold q.mu.
+func (q *FileQueue[T]) write(entry []byte, sync bool) error {
+	_, err := q.f.Write(entry)
+	if err == nil && sync {
+		err = q.f.Sync()
+	}
+	if err == nil {
+		q.end += int64(len(entry))
+		return nil
+	}
+	rerr := q.f.Truncate(q.end)
+	if rerr == nil {
+		_, rerr = q.f.Seek(q.end, io.SeekStart)
+	}
+	if rerr != nil {
+		q.f.Close()
+		q.f = nil
+	}
+	return fmt.Errorf("workerpool: file queue: %w", err)
+}
+
+func (q *FileQueue[T]) Pending() ([]Record[T], error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	out := make([]Record[T], 0, len(q.pending))
+	for id, entry := range q.pending {
+		body := entry[entryHeaderSize:]
+		r := Record[T]{
+			ID:       id,
+			Priority: int(int64(binary.BigEndian.Uint64(body[9:17]))),
+		}
+		if ns := int64(binary.BigEndian.Uint64(body[17:25])); ns != 0 {
+			r.RunAt = time.Unix(0, ns)
+		}
+		data := body[putBodyPrefix:]
+		switch body[0] {
+		case opPutTenant:
+			r.Tenant, data = cutString(data)
+		case opPutKeys:
+			r.Tenant, data = cutString(data)
+			r.Key, data = cutString(data)
+			r.SerialKey, data = cutString(data)
+		}
+		if err := q.codec.Unmarshal(data, &r.Payload); err != nil {
+			return nil, fmt.Errorf("workerpool: file queue: decode record %d: %w", id, err)
+		}
+		out = append(out, r)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+// cutString splits a length-prefixed string off the front of data.
+func cutString(data []byte) (string, []byte) {
+	n := int(binary.BigEndian.Uint16(data))
+	return string(data[strLenSize : strLenSize+n]), data[strLenSize+n:]
+}
+
+// compact rewrites the log with only the pending records: it writes a
+// sibling file, syncs it, renames it over the log and syncs the directory.
+// Callers hold q.mu.
+func (q *FileQueue[T]) compact() error {
+	ids := make([]uint64, 0, len(q.pending))
+	for id := range q.pending {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	tmp := q.path + ".compact"
+	f, err := os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("workerpool: file queue: compact: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	var end int64
+	for _, id := range ids {
+		if _, err = w.Write(q.pending[id]); err != nil {
+			break
+		}
+		end += int64(len(q.pending[id]))
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if err == nil {
+		err = os.Rename(tmp, q.path)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("workerpool: file queue: compact: %w", err)
+	}
+	q.f.Close()
+	q.f = f
+	q.end = end
+	q.acked = 0
+	// Until the directory is synced a crash may bring back the old log,
+	// without the records put to the new one from here on.
+	if err := syncDir(filepath.Dir(q.path)); err != nil {
+		return fmt.Errorf("workerpool: file queue: compact: %w", err)
+	}
+	return nil
+}
+
+// syncDir makes the entries of dir, such as a rename into it, durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if cerr := d.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Close syncs and closes the log file.
+func (q *FileQueue[T]) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.f == nil {
+		return nil
+	}
+	err := q.f.Sync()
+	if cerr := q.f.Close(); err == nil {
+		err = cerr
+	}
+	q.f = nil
+	return err
+}
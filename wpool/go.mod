@@ -3,7 +3,7 @@ module github.com/azargarov/go-utils/wpool
 go 1.23.6
 
 require (
-	github.com/azargarov/go-utils/backoff v0.1.1
+	github.com/azargarov/go-utils/backoff v0.2.0
 	github.com/azargarov/go-utils/zlog v0.2.2
 )
 
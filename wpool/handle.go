@@ -0,0 +1,135 @@
+package workerpool
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Status is the lifecycle state of a submitted job.
+type Status int32
+
+const (
+	StatusQueued    Status = iota // waiting in the queue or for RunAt
+	StatusRunning                 // an attempt is executing
+	StatusRetrying                // backing off between attempts
+	StatusSucceeded               // an attempt returned nil
+	StatusFailed                  // the final attempt failed or the job panicked
+	StatusCanceled                // Job.Ctx was canceled or the pool shut down first
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusQueued:
+		return "queued"
+	case StatusRunning:
+		return "running"
+	case StatusRetrying:
+		return "retrying"
+	case StatusSucceeded:
+		return "succeeded"
+	case StatusFailed:
+		return "failed"
+	case StatusCanceled:
+		return "canceled"
+	}
+	return "unknown"
+}
+
+// Done reports whether s is a final state.
+func (s Status) Done() bool { return s >= StatusSucceeded }
+
+// Result is the outcome of a job.
+type Result[T any] struct {
+	ID       uint64
+	Payload  T
+	Status   Status
+	Err      error // error of the last attempt; nil on success
+	Attempts int
+	Started  time.Time // zero if the job never ran
+	Finished time.Time
+}
+
+// Handle tracks a job submitted with SubmitHandle.
+type Handle[T any] struct {
+	id     uint64
+	status atomic.Int32
+	done   chan struct{}
+	result Result[T] // written once before done is closed
+}
+
+func newHandle[T any](id uint64) *Handle[T] {
+	return &Handle[T]{id: id, done: make(chan struct{})}
+}
+
+// ID returns the job's pool-unique ID.
+func (h *Handle[T]) ID() uint64 { return h.id }
+
+// Status returns the job's current state.
+func (h *Handle[T]) Status() Status { return Status(h.status.Load()) }
+
+// Done is closed once the job reaches a final state.
+func (h *Handle[T]) Done() <-chan struct{} { return h.done }
+
+// Result returns the job's outcome and true once it has finished.
+func (h *Handle[T]) Result() (Result[T], bool) {
+	select {
+	case <-h.done:
+		return h.result, true
+	default:
+		return Result[T]{}, false
+	}
+}
+
+// Wait blocks until the job finishes or ctx is done. The returned error is
+// ctx's error, not the job's; see Result.Err for that.
+func (h *Handle[T]) Wait(ctx context.Context) (Result[T], error) {
+	select {
+	case <-h.done:
+		return h.result, nil
+	case <-ctx.Done():
+		return Result[T]{}, ctx.Err()
+	}
+}
+
+func (h *Handle[T]) setStatus(s Status) {
+	if h != nil {
+		h.status.Store(int32(s))
+	}
+}
+
+func (h *Handle[T]) complete(res Result[T]) {
+	h.result = res
+	h.status.Store(int32(res.Status))
+	close(h.done)
+}
+
+// newTask wraps a prepared job with a fresh ID and Handle.
+func (p *Pool[T]) newTask(job Job[T]) *task[T] {
+	id := p.nextID.Add(1)
+	return &task[T]{job: job, id: id, handle: newHandle[T](id)}
+}
+
+// finish publishes the outcome of one run of t.
+func (p *Pool[T]) finish(t *task[T], res Result[T]) {
+	res.ID, res.Payload, res.Finished = t.id, t.job.Payload, time.Now()
+	p.record(t, res)
+	if t.sched == nil {
+		p.forget(t)
+	}
+	if t.sched == nil && t.handle != nil {
+		if t.job.Key != "" {
+			p.releaseKey(t.job.Key, t.handle, res.Status)
+		}
+		if res.Status.Done() {
+			t.handle.complete(res)
+		} else if t.job.remote != nil {
+			// a remote retry: the Remote hands the job back to its queue
+			t.handle.setStatus(res.Status)
+			close(t.job.remote.requeue)
+		}
+	}
+	if p.results != nil {
+		p.results <- res
+	}
+}
@@ -0,0 +1,120 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHandleWaitAndStatus(t *testing.T) {
+	p := NewPool[int](1, fastRetry)
+	defer p.Stop()
+
+	release := make(chan struct{})
+	h, err := p.SubmitHandle(Job[int]{Payload: 5, Fn: func(int) error {
+		<-release
+		return nil
+	}})
+	if err != nil {
+		t.Fatalf("SubmitHandle: %v", err)
+	}
+	if h.ID() == 0 {
+		t.Fatal("handle has no ID")
+	}
+	waitStatus(t, h, StatusRunning)
+	if _, ok := h.Result(); ok {
+		t.Fatal("Result reported a running job as finished")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := h.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait err = %v; want deadline exceeded", err)
+	}
+
+	close(release)
+	res, err := h.Wait(context.Background())
+	if err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if res.Status != StatusSucceeded || res.Payload != 5 || res.Attempts != 1 || res.Err != nil || res.ID != h.ID() {
+		t.Fatalf("result = %+v", res)
+	}
+	if h.Status() != StatusSucceeded {
+		t.Fatalf("status = %v; want succeeded", h.Status())
+	}
+}
+
+func TestHandleReportsRetryingAndFailure(t *testing.T) {
+	p := NewPool[int](1, fastRetry)
+	defer p.Stop()
+
+	boom := errors.New("boom")
+	h, _ := p.SubmitHandle(Job[int]{
+		Retry: &RetryPolicy{Attempts: 2, Initial: 100 * time.Millisecond, Max: 100 * time.Millisecond},
+		Fn:    func(int) error { return boom },
+	})
+	waitStatus(t, h, StatusRetrying)
+
+	res, _ := h.Wait(context.Background())
+	if res.Status != StatusFailed || !errors.Is(res.Err, boom) || res.Attempts != 2 {
+		t.Fatalf("result = %+v; want failed after 2 attempts", res)
+	}
+}
+
+func TestHandleCanceledDelayedJob(t *testing.T) {
+	p := NewPool[int](1, fastRetry)
+	defer p.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h, _ := p.SubmitHandle(Job[int]{Ctx: ctx, RunAt: time.Now().Add(time.Hour), Fn: func(int) error { return nil }})
+	if h.Status() != StatusQueued {
+		t.Fatalf("status = %v; want queued", h.Status())
+	}
+	cancel()
+	res, _ := h.Wait(context.Background())
+	if res.Status != StatusCanceled || !errors.Is(res.Err, context.Canceled) {
+		t.Fatalf("result = %+v; want canceled", res)
+	}
+}
+
+func TestResultsChannelFanIn(t *testing.T) {
+	results := make(chan Result[int], 10)
+	p := NewPool[int](3, fastRetry, Config[int]{Results: results})
+
+	for i := 1; i <= 5; i++ {
+		_ = p.Submit(Job[int]{Payload: i, Retry: &RetryPolicy{Attempts: 1}, Fn: func(n int) error {
+			if n%2 == 0 {
+				return errors.New("even")
+			}
+			return nil
+		}})
+	}
+	p.Stop()
+	close(results)
+
+	ok, failed := 0, 0
+	for r := range results {
+		switch r.Status {
+		case StatusSucceeded:
+			ok++
+		case StatusFailed:
+			failed++
+		}
+	}
+	if ok != 3 || failed != 2 {
+		t.Fatalf("succeeded=%d failed=%d; want 3 and 2", ok, failed)
+	}
+}
+
+func waitStatus[T any](t *testing.T, h *Handle[T], want Status) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for h.Status() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("status = %v; want %v", h.Status(), want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,141 @@
+package workerpool
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// JobInfo describes a queued, delayed or running job in a Jobs snapshot.
+type JobInfo struct {
+	ID       uint64
+	Payload  string // rendered by Config.Log.Payload; empty without it
+	Status   Status // StatusQueued, StatusRunning or StatusRetrying
+	Priority int
+	Tenant   string
+	Attempt  int       // attempts started by the current run
+	Due      time.Time // next activation of a delayed or recurring job
+	Started  time.Time // zero unless running
+}
+
+// Pause stops handing queued jobs to workers. Running jobs, including their
+// retries, carry on, and submissions are still accepted until the queue is
+// full. Shutdown resumes a paused pool so that it drains.
+func (p *Pool[T]) Pause() {
+	p.mu.Lock()
+	p.paused = true
+	p.mu.Unlock()
+}
+
+// Resume restarts dispatching after Pause.
+func (p *Pool[T]) Resume() {
+	p.mu.Lock()
+	p.paused = false
+	p.mu.Unlock()
+	p.wake()
+}
+
+// Paused reports whether the pool is paused.
+func (p *Pool[T]) Paused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.paused
+}
+
+// Jobs returns a snapshot of the jobs that are queued, delayed, waiting for
+// their SerialKey or batch, or running, ordered by ID.
+func (p *Pool[T]) Jobs() []JobInfo {
+	p.mu.Lock()
+	infos := make([]JobInfo, 0, len(p.jobs))
+	for _, t := range p.jobs {
+		info := JobInfo{
+			ID:       t.id,
+			Status:   StatusQueued,
+			Priority: t.job.Priority,
+			Tenant:   t.job.Tenant,
+			Attempt:  t.attempts,
+			Started:  t.started,
+		}
+		if t.waiting {
+			info.Due = t.due
+		}
+		if !t.started.IsZero() {
+			info.Status = StatusRunning
+			if t.handle != nil {
+				info.Status = t.handle.Status()
+			}
+		}
+		if p.logs.payload != nil {
+			info.Payload = p.logs.payload(t.job.Payload)
+		}
+		infos = append(infos, info)
+	}
+	p.mu.Unlock()
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos
+}
+
+// Cancel cancels the job with the given ID and reports whether it was
+// queued, delayed or running. A queued or delayed job is discarded without
+// running, a running one has its attempt context canceled, and a recurring
+// job stops after that. The job completes as StatusCanceled.
+func (p *Pool[T]) Cancel(id uint64) bool {
+	p.mu.Lock()
+	t, ok := p.jobs[id]
+	if !ok {
+		p.mu.Unlock()
+		return false
+	}
+	cancel, queued, slot := t.cancel, t.queued, t.slot
+	if queued {
+		p.queue.remove(t)
+		t.reclaimed = true // the dispatcher may already be offering it
+		t.slot = false
+	} else if p.dropParked(t) {
+		queued = true // parked behind its SerialKey, it still holds a slot
+		t.slot = false
+	}
+	p.mu.Unlock()
+
+	cancel() // delayed jobs are discarded by their cancellation hook
+	if queued {
+		if slot {
+			p.release(t)
+		}
+		p.releaseSerial(t) // it may be a requeued SerialKey owner
+		p.discard(t, true)
+	}
+	return true
+}
+
+// track registers t for Jobs and Cancel, giving it its own cancelable
+// Job.Ctx. Callers hold p.mu.
+func (p *Pool[T]) track(t *task[T]) {
+	if t.cancel != nil {
+		return
+	}
+	t.job.Ctx, t.cancel = context.WithCancel(t.job.Ctx)
+	p.jobs[t.id] = t
+}
+
+// forget unregisters t once it will not run again.
+func (p *Pool[T]) forget(t *task[T]) {
+	p.mu.Lock()
+	delete(p.jobs, t.id)
+	p.mu.Unlock()
+	if t.cancel != nil {
+		t.cancel()
+	}
+}
+
+func (p *Pool[T]) running(t *task[T], started time.Time) {
+	p.mu.Lock()
+	t.started = started
+	p.mu.Unlock()
+}
+
+func (p *Pool[T]) attempted(t *task[T], attempts int) {
+	p.mu.Lock()
+	t.attempts = attempts
+	p.mu.Unlock()
+}
@@ -0,0 +1,168 @@
+package workerpool
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPauseResume(t *testing.T) {
+	p := NewPool[int](2, fastRetry)
+	defer p.Stop()
+
+	var ran atomic.Int32
+	p.Pause()
+	if !p.Paused() {
+		t.Fatal("Paused() = false after Pause")
+	}
+	for i := 0; i < 3; i++ {
+		_ = p.Submit(Job[int]{Payload: i, Fn: func(int) error { ran.Add(1); return nil }})
+	}
+	time.Sleep(30 * time.Millisecond)
+	if n := ran.Load(); n != 0 {
+		t.Fatalf("%d jobs ran while paused", n)
+	}
+	if jobs := p.Jobs(); len(jobs) != 3 || jobs[0].Status != StatusQueued {
+		t.Fatalf("jobs = %+v; want 3 queued", jobs)
+	}
+
+	p.Resume()
+	deadline := time.Now().Add(time.Second)
+	for ran.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := ran.Load(); n != 3 {
+		t.Fatalf("%d jobs ran after Resume; want 3", n)
+	}
+	if jobs := p.Jobs(); len(jobs) != 0 {
+		t.Fatalf("jobs after completion = %+v", jobs)
+	}
+}
+
+func TestShutdownDrainsPausedPool(t *testing.T) {
+	p := NewPool[int](1, fastRetry)
+	p.Pause()
+	var ran atomic.Int32
+	_ = p.Submit(Job[int]{Fn: func(int) error { ran.Add(1); return nil }})
+	p.Stop()
+	if ran.Load() != 1 {
+		t.Fatal("Shutdown did not drain a paused pool")
+	}
+}
+
+func TestJobsSnapshot(t *testing.T) {
+	p := NewPool[int](1, fastRetry, Config[int]{Log: LogConfig[int]{
+		Payload: func(n int) string { return "n=" + strconv.Itoa(n) },
+	}})
+	defer p.Stop()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	running, _ := p.SubmitHandle(Job[int]{Payload: 7, Fn: func(int) error {
+		close(started)
+		<-release
+		return nil
+	}})
+	<-started
+	due := time.Now().Add(time.Hour)
+	delayed, _ := p.SubmitHandle(Job[int]{Payload: 8, RunAt: due, Fn: func(int) error { return nil }})
+
+	jobs := p.Jobs()
+	if len(jobs) != 2 {
+		t.Fatalf("jobs = %+v; want 2", jobs)
+	}
+	r, d := jobs[0], jobs[1]
+	if r.ID != running.ID() || r.Status != StatusRunning || r.Attempt != 1 || r.Started.IsZero() || r.Payload != "n=7" {
+		t.Fatalf("running job = %+v", r)
+	}
+	if d.ID != delayed.ID() || d.Status != StatusQueued || !d.Due.Equal(due) || !d.Started.IsZero() || d.Payload != "n=8" {
+		t.Fatalf("delayed job = %+v", d)
+	}
+	close(release)
+}
+
+func TestCancelByID(t *testing.T) {
+	p := NewPool[int](1, fastRetry)
+	defer p.Stop()
+
+	// running: its attempt context is canceled
+	started := make(chan struct{})
+	running, _ := p.SubmitHandle(Job[int]{FnCtx: func(ctx context.Context, _ int) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+	<-started
+
+	// queued behind it and delayed: discarded without running
+	var ran atomic.Bool
+	queued, _ := p.SubmitHandle(Job[int]{Fn: func(int) error { ran.Store(true); return nil }})
+	delayed, _ := p.SubmitHandle(Job[int]{RunAt: time.Now().Add(time.Hour), Fn: func(int) error { ran.Store(true); return nil }})
+
+	for _, h := range []*Handle[int]{queued, delayed, running} {
+		if !p.Cancel(h.ID()) {
+			t.Fatalf("Cancel(%d) = false", h.ID())
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		res, err := h.Wait(ctx)
+		cancel()
+		if err != nil || res.Status != StatusCanceled {
+			t.Fatalf("job %d result = %+v, %v; want canceled", h.ID(), res, err)
+		}
+	}
+	if ran.Load() {
+		t.Fatal("a canceled job ran")
+	}
+	if p.Cancel(running.ID()) || p.Cancel(12345) {
+		t.Fatal("Cancel of a finished or unknown job reported true")
+	}
+	if jobs := p.Jobs(); len(jobs) != 0 {
+		t.Fatalf("jobs = %+v; want none", jobs)
+	}
+}
+
+func TestCancelParkedJob(t *testing.T) {
+	p := NewPool[int](2, fastRetry)
+	defer p.Stop()
+
+	gate := make(chan struct{})
+	owner, _ := p.SubmitHandle(Job[int]{SerialKey: "k", Fn: func(int) error { <-gate; return nil }})
+	var ran atomic.Bool
+	parked, _ := p.SubmitHandle(Job[int]{SerialKey: "k", Fn: func(int) error { ran.Store(true); return nil }})
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		p.mu.Lock()
+		n := p.parked
+		p.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("job was not parked behind its SerialKey")
+		}
+	}
+
+	if !p.Cancel(parked.ID()) {
+		t.Fatal("Cancel of a parked job = false")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	res, err := parked.Wait(ctx)
+	cancel()
+	if err != nil || res.Status != StatusCanceled {
+		t.Fatalf("parked job result = %+v, %v; want canceled while its key is busy", res, err)
+	}
+
+	close(gate)
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if res, err := owner.Wait(ctx); err != nil || res.Status != StatusSucceeded {
+		t.Fatalf("owner result = %+v, %v", res, err)
+	}
+	if ran.Load() {
+		t.Fatal("the canceled parked job ran")
+	}
+	if jobs := p.Jobs(); len(jobs) != 0 {
+		t.Fatalf("jobs = %+v; want none", jobs)
+	}
+}
@@ -0,0 +1,125 @@
+package workerpool
+
+import (
+	"context"
+
+	lg "github.com/azargarov/go-utils/zlog"
+)
+
+// LogEvent identifies a job lifecycle event the pool logs.
+type LogEvent int
+
+const (
+	LogSubmit  LogEvent = iota // job queued or scheduled
+	LogStart                   // a worker started the job
+	LogSuccess                 // the job succeeded
+	LogRetry                   // an attempt failed and will be retried
+	LogFailure                 // the job failed for good
+	LogCancel                  // the job was canceled, skipped or aborted
+	LogPanic                   // the job panicked
+	LogDiscard                 // a waiting job was dropped without running
+	logEvents
+)
+
+// LogLevel is the level an event is logged at.
+type LogLevel int
+
+const (
+	LevelDefault LogLevel = iota // the event's default level
+	LevelDebug
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelOff // do not log the event
+)
+
+var defaultLogLevels = [logEvents]LogLevel{
+	LogSubmit:  LevelDebug,
+	LogStart:   LevelDebug,
+	LogSuccess: LevelDebug,
+	LogRetry:   LevelWarn,
+	LogFailure: LevelError,
+	LogCancel:  LevelInfo,
+	LogPanic:   LevelError,
+	LogDiscard: LevelInfo,
+}
+
+// LogConfig controls what the pool logs about jobs.
+type LogConfig[T any] struct {
+	// Logger receives all pool logs. Nil uses the logger attached to Job.Ctx
+	// (see zlog.Attach), or zlog's fallback logger.
+	Logger lg.ZLogger
+	// Levels overrides the level of individual events. By default submit,
+	// start and success log at Debug, cancel and discard at Info, retries at
+	// Warn, and failures and panics at Error.
+	Levels map[LogEvent]LogLevel
+	// Sample, if above 1, keeps the Debug and Info logs of only one job in
+	// Sample (chosen by job ID, so a sampled job is logged completely).
+	// Warn and Error logs are never sampled.
+	Sample int
+	// Payload renders the payload for logs, e.g. to redact or summarize it
+	// (autostr.String with a restricted Config works well). Nil logs only
+	// the job ID.
+	Payload func(T) string
+}
+
+// logSettings is the resolved LogConfig.
+type logSettings[T any] struct {
+	logger  lg.ZLogger
+	levels  [logEvents]LogLevel
+	sample  uint64
+	payload func(T) string
+}
+
+func newLogSettings[T any](cfg LogConfig[T]) logSettings[T] {
+	s := logSettings[T]{logger: cfg.Logger, levels: defaultLogLevels, payload: cfg.Payload}
+	for ev, lvl := range cfg.Levels {
+		if ev >= 0 && ev < logEvents && lvl != LevelDefault {
+			s.levels[ev] = lvl
+		}
+	}
+	if cfg.Sample > 1 {
+		s.sample = uint64(cfg.Sample)
+	}
+	return s
+}
+
+// logger returns the pool logger, or the one attached to ctx.
+func (p *Pool[T]) logger(ctx context.Context) lg.ZLogger {
+	if p.logs.logger != nil {
+		return p.logs.logger
+	}
+	return lg.FromContext(ctx)
+}
+
+// jobLog logs the lifecycle events of one job.
+type jobLog struct {
+	l       lg.ZLogger
+	levels  *[logEvents]LogLevel
+	sampled bool // false if sampling dropped this job's low-level logs
+}
+
+func (p *Pool[T]) jobLog(ctx context.Context, t *task[T]) jobLog {
+	fields := []lg.Field{lg.Any("job_id", t.id)}
+	if p.logs.payload != nil {
+		fields = append(fields, lg.String("job", p.logs.payload(t.job.Payload)))
+	}
+	return jobLog{
+		l:       p.logger(ctx).With(fields...),
+		levels:  &p.logs.levels,
+		sampled: p.logs.sample == 0 || t.id%p.logs.sample == 0,
+	}
+}
+
+func (j jobLog) log(ev LogEvent, msg string, fields ...lg.Field) {
+	switch lvl := j.levels[ev]; {
+	case lvl == LevelDebug && j.sampled:
+		j.l.Debug(msg, fields...)
+	case lvl == LevelInfo && j.sampled:
+		j.l.Info(msg, fields...)
+	case lvl == LevelWarn:
+		j.l.Warn(msg, fields...)
+	case lvl == LevelError:
+		j.l.Error(msg, fields...)
+	}
+}
@@ -0,0 +1,121 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	lg "github.com/azargarov/go-utils/zlog"
+)
+
+// recLogger records each message as "LEVEL msg key[=string value]...".
+type recLogger struct {
+	lg.ZLogger
+	mu     *sync.Mutex
+	lines  *[]string
+	fields []lg.Field
+}
+
+func newRecLogger() *recLogger {
+	return &recLogger{ZLogger: lg.NewDiscard(), mu: &sync.Mutex{}, lines: new([]string)}
+}
+
+func (r *recLogger) With(fields ...lg.Field) lg.ZLogger {
+	c := *r
+	c.fields = append(append([]lg.Field(nil), r.fields...), fields...)
+	return &c
+}
+
+func (r *recLogger) add(level, msg string, fields []lg.Field) {
+	line := level + " " + msg
+	for _, f := range append(r.fields, fields...) {
+		line += " " + f.Key
+		if f.String != "" {
+			line += "=" + f.String
+		}
+	}
+	r.mu.Lock()
+	*r.lines = append(*r.lines, line)
+	r.mu.Unlock()
+}
+
+func (r *recLogger) Debug(msg string, fields ...lg.Field) { r.add("DEBUG", msg, fields) }
+func (r *recLogger) Info(msg string, fields ...lg.Field)  { r.add("INFO", msg, fields) }
+func (r *recLogger) Warn(msg string, fields ...lg.Field)  { r.add("WARN", msg, fields) }
+func (r *recLogger) Error(msg string, fields ...lg.Field) { r.add("ERROR", msg, fields) }
+
+func (r *recLogger) all() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), *r.lines...)
+}
+
+func TestLogLevelsAndRedaction(t *testing.T) {
+	rec := newRecLogger()
+	p := NewPool[string](1, fastRetry, Config[string]{Log: LogConfig[string]{
+		Logger:  rec,
+		Levels:  map[LogEvent]LogLevel{LogSubmit: LevelOff, LogSuccess: LevelInfo},
+		Payload: func(s string) string { return strings.Repeat("*", len(s)) },
+	}})
+	h, _ := p.SubmitHandle(Job[string]{Payload: "secret", Fn: func(string) error { return nil }})
+	_, _ = h.Wait(context.Background())
+	p.Stop()
+
+	var sawSuccess bool
+	for _, line := range rec.all() {
+		if strings.Contains(line, "secret") {
+			t.Fatalf("payload leaked: %q", line)
+		}
+		if strings.Contains(line, "submitted") {
+			t.Fatalf("disabled event logged: %q", line)
+		}
+		if strings.HasPrefix(line, "INFO Worker finished") {
+			sawSuccess = true
+			if !strings.Contains(line, "job=******") || !strings.Contains(line, "job_id") {
+				t.Fatalf("success line = %q; want redacted payload and job_id", line)
+			}
+		}
+		if !strings.HasPrefix(line, "DEBUG Worker processing") && !strings.HasPrefix(line, "INFO Worker finished") {
+			t.Fatalf("unexpected log line %q", line)
+		}
+	}
+	if !sawSuccess {
+		t.Fatalf("no success log at Info in %v", rec.all())
+	}
+}
+
+func TestLogSampling(t *testing.T) {
+	rec := newRecLogger()
+	p := NewPool[int](1, fastRetry, Config[int]{Log: LogConfig[int]{Logger: rec, Sample: 4}})
+	var hs []*Handle[int]
+	for i := 0; i < 8; i++ {
+		h, _ := p.SubmitHandle(Job[int]{Fn: func(int) error { return nil }})
+		hs = append(hs, h)
+	}
+	fail, _ := p.SubmitHandle(Job[int]{
+		Retry: &RetryPolicy{Attempts: 1},
+		Fn:    func(int) error { return errors.New("boom") },
+	})
+	for _, h := range append(hs, fail) {
+		_, _ = h.Wait(context.Background())
+	}
+	p.Stop()
+
+	submits, failures := 0, 0
+	for _, line := range rec.all() {
+		switch {
+		case strings.HasPrefix(line, "DEBUG Job submitted"):
+			submits++
+		case strings.HasPrefix(line, "ERROR Job failed"):
+			failures++
+		}
+	}
+	if submits != 2 {
+		t.Fatalf("sampled submit logs = %d; want 2 of 9", submits)
+	}
+	if failures != 1 {
+		t.Fatalf("failure logs = %d; want 1 (never sampled)", failures)
+	}
+}
@@ -0,0 +1,245 @@
+package workerpool
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	lg "github.com/azargarov/go-utils/zlog"
+)
+
+// Event describes a job at one point of its lifecycle. Fields that do not
+// apply to a hook are zero.
+type Event[T any] struct {
+	ID       uint64
+	Payload  T
+	Priority int
+	Attempt  int           // OnRetry, OnSuccess, OnFailure
+	Err      error         // OnRetry, OnFailure
+	Delay    time.Duration // OnRetry: backoff before the next attempt
+	Wait     time.Duration // OnStart: time spent queued
+	Elapsed  time.Duration // OnSuccess, OnFailure: time since the job started
+}
+
+// Hooks observe the job lifecycle. They run synchronously on the submitting
+// or worker goroutine, so they must be fast and must not block. OnSubmit runs
+// once the job is queued and may overlap with its OnStart.
+type Hooks[T any] struct {
+	OnSubmit  func(ctx context.Context, e Event[T])
+	OnStart   func(ctx context.Context, e Event[T])
+	OnRetry   func(ctx context.Context, e Event[T])
+	OnSuccess func(ctx context.Context, e Event[T])
+	OnFailure func(ctx context.Context, e Event[T])
+
+	// StartSpan is the tracing integration point. It is called with Job.Ctx
+	// when a job starts running, so the span becomes a child of whatever span
+	// the submitter stored there. The returned context is passed to the job
+	// function and to the other hooks; end is called with the job's final
+	// error once all attempts are done.
+	StartSpan func(ctx context.Context, e Event[T]) (_ context.Context, end func(err error))
+}
+
+// Stats is a snapshot of the pool's counters and histograms.
+type Stats struct {
+	Submitted   uint64
+	Started     uint64
+	Succeeded   uint64
+	Failed      uint64
+	Canceled    uint64
+	Retries     uint64
+	Panics      uint64
+	DeadLetters uint64
+	Dropped     uint64 // shed by OverflowDropOldest or OverflowDropNewest
+
+	// Tenants holds the counters of every non-empty Job.Tenant seen.
+	Tenants map[string]TenantStats
+
+	QueueWait Histogram // seconds between being queued (or due) and starting
+	ExecTime  Histogram // seconds from start to finish, including backoff
+	Attempts  Histogram // attempts per succeeded or failed job
+}
+
+// Histogram holds non-cumulative bucket counts: Counts[i] observations fell
+// in (Bounds[i-1], Bounds[i]], and the extra last element of Counts holds
+// observations above every bound.
+type Histogram struct {
+	Bounds []float64
+	Counts []uint64
+	Count  uint64
+	Sum    float64
+}
+
+var (
+	durationBounds = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60}
+	attemptBounds  = []float64{1, 2, 3, 5, 10, 20}
+)
+
+type histogram struct {
+	mu     sync.Mutex
+	bounds []float64
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{bounds: bounds, counts: make([]uint64, len(bounds)+1)}
+}
+
+func (h *histogram) observe(v float64) {
+	i := sort.SearchFloat64s(h.bounds, v)
+	h.mu.Lock()
+	h.counts[i]++
+	h.count++
+	h.sum += v
+	h.mu.Unlock()
+}
+
+func (h *histogram) snapshot() Histogram {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return Histogram{
+		Bounds: append([]float64(nil), h.bounds...),
+		Counts: append([]uint64(nil), h.counts...),
+		Count:  h.count,
+		Sum:    h.sum,
+	}
+}
+
+type metrics struct {
+	submitted, started, succeeded, failed, canceled atomic.Uint64
+	retries, panics, deadLetters, dropped           atomic.Uint64
+
+	queueWait, execTime, attempts *histogram
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		queueWait: newHistogram(durationBounds),
+		execTime:  newHistogram(durationBounds),
+		attempts:  newHistogram(attemptBounds),
+	}
+}
+
+// Stats returns a snapshot of the pool's metrics.
+func (p *Pool[T]) Stats() Stats {
+	m := p.metrics
+	return Stats{
+		Submitted:   m.submitted.Load(),
+		Started:     m.started.Load(),
+		Succeeded:   m.succeeded.Load(),
+		Failed:      m.failed.Load(),
+		Canceled:    m.canceled.Load(),
+		Retries:     m.retries.Load(),
+		Panics:      m.panics.Load(),
+		DeadLetters: m.deadLetters.Load(),
+		Dropped:     m.dropped.Load(),
+		QueueWait:   m.queueWait.snapshot(),
+		ExecTime:    m.execTime.snapshot(),
+		Attempts:    m.attempts.snapshot(),
+		Tenants:     p.tenantSnapshot(),
+	}
+}
+
+func (p *Pool[T]) event(t *task[T]) Event[T] {
+	return Event[T]{ID: t.id, Payload: t.job.Payload, Priority: t.job.Priority}
+}
+
+func (p *Pool[T]) submitted(t *task[T]) {
+	p.metrics.submitted.Add(1)
+	if m := p.tenantMetrics(t.job.Tenant); m != nil {
+		m.submitted.Add(1)
+	}
+	if t.due.IsZero() {
+		p.jobLog(t.job.Ctx, t).log(LogSubmit, "Job submitted")
+	} else {
+		p.jobLog(t.job.Ctx, t).log(LogSubmit, "Job scheduled", lg.Time("run_at", t.due))
+	}
+	if p.hooks.OnSubmit != nil {
+		p.hooks.OnSubmit(t.job.Ctx, p.event(t))
+	}
+}
+
+func (p *Pool[T]) started(ctx context.Context, t *task[T]) {
+	p.metrics.started.Add(1)
+	if m := p.tenantMetrics(t.job.Tenant); m != nil {
+		m.started.Add(1)
+	}
+	wait := time.Since(t.enqueued)
+	p.metrics.queueWait.observe(wait.Seconds())
+	if p.hooks.OnStart != nil {
+		e := p.event(t)
+		e.Wait = wait
+		p.hooks.OnStart(ctx, e)
+	}
+}
+
+func (p *Pool[T]) retrying(ctx context.Context, t *task[T], attempt int, err error, delay time.Duration) {
+	p.metrics.retries.Add(1)
+	if p.hooks.OnRetry != nil {
+		e := p.event(t)
+		e.Attempt, e.Err, e.Delay = attempt, err, delay
+		p.hooks.OnRetry(ctx, e)
+	}
+}
+
+// startSpan opens the job's trace span, if tracing is configured.
+func (p *Pool[T]) startSpan(t *task[T]) (context.Context, func(error)) {
+	noop := func(error) {}
+	if p.hooks.StartSpan == nil {
+		return t.job.Ctx, noop
+	}
+	ctx, end := p.hooks.StartSpan(t.job.Ctx, p.event(t))
+	if ctx == nil {
+		ctx = t.job.Ctx
+	}
+	if end == nil {
+		end = noop
+	}
+	return ctx, end
+}
+
+// ran reports the outcome of a job that a worker executed to the hooks.
+func (p *Pool[T]) ran(ctx context.Context, t *task[T], res Result[T]) {
+	e := p.event(t)
+	e.Attempt, e.Err, e.Elapsed = res.Attempts, res.Err, time.Since(res.Started)
+	switch {
+	case res.Status == StatusSucceeded && p.hooks.OnSuccess != nil:
+		p.hooks.OnSuccess(ctx, e)
+	case res.Status == StatusFailed && p.hooks.OnFailure != nil:
+		p.hooks.OnFailure(ctx, e)
+	}
+}
+
+// record counts the outcome of one run; res.Finished is set.
+func (p *Pool[T]) record(t *task[T], res Result[T]) {
+	m := p.metrics
+	tm := p.tenantMetrics(t.job.Tenant)
+	switch res.Status {
+	case StatusSucceeded:
+		m.succeeded.Add(1)
+		if tm != nil {
+			tm.succeeded.Add(1)
+		}
+	case StatusFailed:
+		m.failed.Add(1)
+		if tm != nil {
+			tm.failed.Add(1)
+		}
+	case StatusRetrying:
+		// a remote attempt returned to its queue; retrying counted it
+		return
+	default:
+		m.canceled.Add(1)
+		if tm != nil {
+			tm.canceled.Add(1)
+		}
+		return
+	}
+	m.attempts.observe(float64(res.Attempts))
+	if !res.Started.IsZero() {
+		m.execTime.observe(res.Finished.Sub(res.Started).Seconds())
+	}
+}
@@ -0,0 +1,100 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+type spanKey struct{}
+
+func TestHooksAndSpan(t *testing.T) {
+	var mu sync.Mutex
+	var seen []string
+	var spanErr error
+	note := func(name string) func(context.Context, Event[int]) {
+		return func(ctx context.Context, e Event[int]) {
+			if name != "submit" && ctx.Value(spanKey{}) == nil {
+				t.Errorf("%s hook did not get the span context", name)
+			}
+			mu.Lock()
+			seen = append(seen, name)
+			mu.Unlock()
+		}
+	}
+	p := NewPool[int](1, fastRetry, Config[int]{Hooks: Hooks[int]{
+		OnSubmit:  note("submit"),
+		OnStart:   note("start"),
+		OnRetry:   note("retry"),
+		OnSuccess: note("success"),
+		OnFailure: note("failure"),
+		StartSpan: func(ctx context.Context, e Event[int]) (context.Context, func(error)) {
+			return context.WithValue(ctx, spanKey{}, e.ID), func(err error) {
+				mu.Lock()
+				spanErr = err
+				mu.Unlock()
+			}
+		},
+	}})
+	defer p.Stop()
+
+	calls := 0
+	h, _ := p.SubmitHandle(Job[int]{FnCtx: func(ctx context.Context, _ int) error {
+		if ctx.Value(spanKey{}) == nil {
+			t.Error("job did not get the span context")
+		}
+		calls++
+		if calls == 1 {
+			return errors.New("transient")
+		}
+		return nil
+	}})
+	_, _ = h.Wait(context.Background())
+	p.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"submit", "start", "retry", "success"}
+	if len(seen) != len(want) {
+		t.Fatalf("hooks = %v; want %v", seen, want)
+	}
+	for i := range want {
+		if seen[i] != want[i] {
+			t.Fatalf("hooks = %v; want %v", seen, want)
+		}
+	}
+	if spanErr != nil {
+		t.Fatalf("span ended with %v; want nil", spanErr)
+	}
+}
+
+func TestStats(t *testing.T) {
+	dls := make(chan DeadLetter[int], 2)
+	p := NewPool[int](1, fastRetry, Config[int]{DeadLetter: DeadLetterChan[int](dls)})
+
+	ok, _ := p.SubmitHandle(Job[int]{Fn: func(int) error { return nil }})
+	bad, _ := p.SubmitHandle(Job[int]{Fn: func(int) error { return errors.New("boom") }})
+	boom, _ := p.SubmitHandle(Job[int]{Fn: func(int) error { panic("boom") }})
+	for _, h := range []*Handle[int]{ok, bad, boom} {
+		_, _ = h.Wait(context.Background())
+	}
+	p.Stop()
+
+	s := p.Stats()
+	if s.Submitted != 3 || s.Started != 3 || s.Succeeded != 1 || s.Failed != 2 {
+		t.Fatalf("counters = %+v", s)
+	}
+	if s.Retries != 2 || s.Panics != 1 || s.DeadLetters != 2 {
+		t.Fatalf("retries/panics/dead letters = %d/%d/%d; want 2/1/2", s.Retries, s.Panics, s.DeadLetters)
+	}
+	if s.QueueWait.Count != 3 || s.ExecTime.Count != 3 || s.Attempts.Count != 3 {
+		t.Fatalf("histogram counts = %d/%d/%d; want 3 each", s.QueueWait.Count, s.ExecTime.Count, s.Attempts.Count)
+	}
+	if s.Attempts.Sum != 1+3+1 {
+		t.Fatalf("attempts sum = %v; want 5", s.Attempts.Sum)
+	}
+	if len(s.Attempts.Counts) != len(s.Attempts.Bounds)+1 {
+		t.Fatal("histogram has no overflow bucket")
+	}
+}
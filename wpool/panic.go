@@ -0,0 +1,54 @@
+package workerpool
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is the error of an attempt whose job function panicked.
+type PanicError struct {
+	Value any    // the value passed to panic
+	Stack []byte // stack of the panicking goroutine
+}
+
+func (e *PanicError) Error() string { return fmt.Sprintf("workerpool: job panicked: %v", e.Value) }
+
+// Unwrap returns the panic value if it is an error, so errors.Is and
+// RetryRule matchers see through the panic.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// PanicPolicy selects what happens to a job whose function panics.
+type PanicPolicy int
+
+const (
+	// PanicFail fails the job at once, without retries. It is dead-lettered
+	// like any other failed job.
+	PanicFail PanicPolicy = iota
+	// PanicRetry treats the *PanicError like any other error under the
+	// job's RetryPolicy.
+	PanicRetry
+	// PanicCrash logs the panic and panics again in the worker, crashing
+	// the process.
+	PanicCrash
+)
+
+// guard calls fn, converting a panic into a *PanicError.
+func guard(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return fn()
+}
+
+// recovered counts a logged panic and applies PanicCrash.
+func (p *Pool[T]) recovered(pe *PanicError) {
+	p.metrics.panics.Add(1)
+	if p.onPanic == PanicCrash {
+		panic(pe)
+	}
+}
@@ -0,0 +1,88 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPanicFailDeadLetters(t *testing.T) {
+	dls := make(chan DeadLetter[int], 1)
+	p := NewPool[int](1, fastRetry, Config[int]{DeadLetter: DeadLetterChan[int](dls)})
+	defer p.Stop()
+
+	var calls atomic.Int32
+	h, _ := p.SubmitHandle(Job[int]{Fn: func(int) error {
+		calls.Add(1)
+		panic("boom")
+	}})
+	res, _ := h.Wait(context.Background())
+	var pe *PanicError
+	if res.Status != StatusFailed || !errors.As(res.Err, &pe) {
+		t.Fatalf("result = %+v; want failed with *PanicError", res)
+	}
+	if pe.Value != "boom" || !strings.Contains(string(pe.Stack), "panic_test.go") {
+		t.Fatalf("panic error = %v, stack:\n%s", pe.Value, pe.Stack)
+	}
+	if calls.Load() != 1 || res.Attempts != 1 {
+		t.Fatalf("ran %d times, %d attempts; want no retries", calls.Load(), res.Attempts)
+	}
+	dl := <-dls
+	if !errors.As(dl.Err, &pe) || len(dl.Attempts) != 1 {
+		t.Fatalf("dead letter = %+v", dl)
+	}
+	if s := p.Stats(); s.Panics != 1 {
+		t.Fatalf("Panics = %d; want 1", s.Panics)
+	}
+}
+
+func TestPanicRetry(t *testing.T) {
+	p := NewPool[int](1, fastRetry, Config[int]{OnPanic: PanicRetry})
+	defer p.Stop()
+
+	errBroken := errors.New("broken")
+	var calls atomic.Int32
+	h, _ := p.SubmitHandle(Job[int]{Fn: func(int) error {
+		if calls.Add(1) < 3 {
+			panic(errBroken)
+		}
+		return nil
+	}})
+	if res, _ := h.Wait(context.Background()); res.Status != StatusSucceeded || res.Attempts != 3 {
+		t.Fatalf("result = %+v; want success on attempt 3", res)
+	}
+
+	// the panic value is visible to retry classification
+	h, _ = p.SubmitHandle(Job[int]{
+		Retry: &RetryPolicy{IsRetryable: func(err error) bool { return !errors.Is(err, errBroken) }},
+		Fn:    func(int) error { panic(errBroken) },
+	})
+	if res, _ := h.Wait(context.Background()); res.Status != StatusFailed || res.Attempts != 1 {
+		t.Fatalf("result = %+v; want failure without retry", res)
+	}
+	if s := p.Stats(); s.Panics != 3 {
+		t.Fatalf("Panics = %d; want 3", s.Panics)
+	}
+}
+
+func TestPanicCrash(t *testing.T) {
+	if os.Getenv("WPOOL_PANIC_CRASH") == "1" {
+		p := NewPool[int](1, fastRetry, Config[int]{OnPanic: PanicCrash})
+		h, _ := p.SubmitHandle(Job[int]{Fn: func(int) error { panic("boom") }})
+		_, _ = h.Wait(context.Background())
+		return
+	}
+	cmd := exec.Command(os.Args[0], "-test.run=^TestPanicCrash$")
+	cmd.Env = append(os.Environ(), "WPOOL_PANIC_CRASH=1")
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatal("process survived a panic under PanicCrash")
+	}
+	if !strings.Contains(string(out), "workerpool: job panicked: boom") {
+		t.Fatalf("crash output:\n%s", out)
+	}
+}
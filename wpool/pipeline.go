@@ -0,0 +1,105 @@
+package workerpool
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// StageFunc transforms one input of a pipeline stage into its output.
+type StageFunc[In, Out any] func(ctx context.Context, in In) (Out, error)
+
+// Stage is a pipeline step backed by its own Pool, so each stage has its own
+// worker count, retry policy and queue. Outputs of a successful run are
+// submitted to the downstream stage set with Pipe; a stage without one
+// discards them.
+type Stage[In, Out any] struct {
+	pool *Pool[In]
+	fn   StageFunc[In, Out]
+	next atomic.Pointer[stageLink[Out]] // set by Pipe; replayed jobs may already be running
+}
+
+// stageLink connects a stage to its downstream stage.
+type stageLink[Out any] struct {
+	emit func(ctx, jobCtx context.Context, out Out) error
+	down func(ctx context.Context) error
+}
+
+// NewStage starts a stage running fn on a pool created with NewPool. The
+// stage installs its own HandlerCtx so replayed jobs go through fn too.
+func NewStage[In, Out any](workers int, retry RetryPolicy, fn StageFunc[In, Out], config ...Config[In]) *Stage[In, Out] {
+	var cfg Config[In]
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	s := &Stage[In, Out]{fn: fn}
+	cfg.Handler = nil
+	cfg.HandlerCtx = func(ctx context.Context, in In) error {
+		return s.run(ctx, context.Background(), in, new(stageOutput[Out]))
+	}
+	s.pool = NewPool[In](workers, retry, cfg)
+	return s
+}
+
+// Pipe forwards the outputs of from to to. Forwarding uses SubmitContext,
+// so a full downstream queue holds up from's workers and, in turn, its
+// producers. Call Pipe before submitting to from; a stage has at most one
+// downstream stage and a later Pipe replaces it.
+func Pipe[A, B, C any](from *Stage[A, B], to *Stage[B, C]) {
+	from.next.Store(&stageLink[B]{
+		emit: func(ctx, jobCtx context.Context, out B) error {
+			return to.pool.SubmitContext(ctx, Job[B]{Payload: out, Ctx: jobCtx})
+		},
+		down: to.Shutdown,
+	})
+}
+
+// stageOutput keeps a job's output across attempts, so a failed forward is
+// retried without running the stage function again.
+type stageOutput[Out any] struct {
+	out Out
+	ok  bool
+}
+
+func (s *Stage[In, Out]) run(ctx, jobCtx context.Context, in In, o *stageOutput[Out]) error {
+	if !o.ok {
+		out, err := s.fn(ctx, in)
+		if err != nil {
+			return err
+		}
+		o.out, o.ok = out, true
+	}
+	next := s.next.Load()
+	if next == nil {
+		return nil
+	}
+	return next.emit(ctx, jobCtx, o.out)
+}
+
+// Submit queues in like Pool.SubmitContext, so under OverflowBlock it waits
+// while the stage's queue is full. ctx becomes the job's context and is
+// passed on to downstream stages.
+func (s *Stage[In, Out]) Submit(ctx context.Context, in In) error {
+	o := new(stageOutput[Out])
+	return s.pool.SubmitContext(ctx, Job[In]{
+		Payload: in,
+		Ctx:     ctx,
+		FnCtx: func(attemptCtx context.Context, in In) error {
+			return s.run(attemptCtx, ctx, in, o)
+		},
+	})
+}
+
+// Pool returns the stage's pool, e.g. for Stats or SubmitHandle.
+func (s *Stage[In, Out]) Pool() *Pool[In] { return s.pool }
+
+// Shutdown drains the stage and then shuts down its downstream stages in
+// order, so every forwarded output is processed.
+func (s *Stage[In, Out]) Shutdown(ctx context.Context) error {
+	if err := s.pool.Shutdown(ctx); err != nil {
+		return err
+	}
+	if next := s.next.Load(); next != nil {
+		return next.down(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPipelineStages(t *testing.T) {
+	var mu sync.Mutex
+	var stored []int
+	var enrichCalls, storeCalls atomic.Int32
+
+	parse := NewStage(2, fastRetry, func(_ context.Context, s string) (int, error) {
+		return strconv.Atoi(s)
+	})
+	enrich := NewStage(3, fastRetry, func(_ context.Context, n int) (int, error) {
+		if enrichCalls.Add(1) == 1 {
+			return 0, errors.New("flaky")
+		}
+		return n * 10, nil
+	})
+	store := NewStage(1, fastRetry, func(_ context.Context, n int) (struct{}, error) {
+		if storeCalls.Add(1) == 1 {
+			return struct{}{}, errors.New("db down")
+		}
+		mu.Lock()
+		stored = append(stored, n)
+		mu.Unlock()
+		return struct{}{}, nil
+	})
+	Pipe(parse, enrich)
+	Pipe(enrich, store)
+
+	for _, s := range []string{"1", "2", "3"} {
+		if err := parse.Submit(context.Background(), s); err != nil {
+			t.Fatalf("submit %s: %v", s, err)
+		}
+	}
+	if err := parse.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	sort.Ints(stored)
+	if len(stored) != 3 || stored[0] != 10 || stored[1] != 20 || stored[2] != 30 {
+		t.Fatalf("stored %v; want [10 20 30]", stored)
+	}
+	if n := enrichCalls.Load(); n != 4 {
+		t.Fatalf("enrich ran %d times; want 4 (one retry)", n)
+	}
+	if s := store.Pool().Stats(); s.Succeeded != 3 {
+		t.Fatalf("store succeeded %d; want 3", s.Succeeded)
+	}
+}
+
+func TestPipelineBackpressure(t *testing.T) {
+	gate := make(chan struct{})
+	src := NewStage(1, fastRetry, func(_ context.Context, n int) (int, error) { return n, nil },
+		Config[int]{QueueCapacity: 1})
+	sink := NewStage(1, fastRetry, func(_ context.Context, n int) (int, error) {
+		<-gate
+		return n, nil
+	}, Config[int]{QueueCapacity: 1})
+	Pipe(src, sink)
+
+	// sink runs one and queues one, src's worker blocks forwarding the
+	// third, src queues the fourth: the fifth must wait.
+	for i := 0; i < 4; i++ {
+		if err := src.Submit(context.Background(), i); err != nil {
+			t.Fatalf("submit %d: %v", i, err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := src.Submit(ctx, 4); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("submit past capacity: err = %v; want deadline exceeded", err)
+	}
+
+	close(gate)
+	if err := src.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if s := sink.Pool().Stats(); s.Succeeded != 4 {
+		t.Fatalf("sink succeeded %d; want 4", s.Succeeded)
+	}
+}
+
+func TestStageRetriesForwardOnly(t *testing.T) {
+	var calls, forwards atomic.Int32
+	s := NewStage(1, fastRetry, func(_ context.Context, n int) (int, error) {
+		calls.Add(1)
+		return n + 1, nil
+	})
+	s.next.Store(&stageLink[int]{
+		emit: func(_, _ context.Context, out int) error {
+			if forwards.Add(1) == 1 {
+				return ErrQueueFull
+			}
+			return nil
+		},
+		down: func(context.Context) error { return nil },
+	})
+	if err := s.Submit(context.Background(), 1); err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if calls.Load() != 1 || forwards.Load() != 2 {
+		t.Fatalf("fn ran %d times, forwarded %d times; want 1 and 2", calls.Load(), forwards.Load())
+	}
+}
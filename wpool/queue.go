@@ -0,0 +1,181 @@
+package workerpool
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	lg "github.com/azargarov/go-utils/zlog"
+)
+
+// Record is the persisted form of a job. Functions, contexts and retry
+// overrides cannot be stored; replayed jobs run with Config.Handler and the
+// pool's default retry policy. Key and SerialKey are kept, so replayed jobs
+// are still deduplicated and serialized.
+type Record[T any] struct {
+	ID        uint64
+	Payload   T
+	Priority  int
+	RunAt     time.Time
+	Tenant    string
+	Key       string
+	SerialKey string
+}
+
+// Queue journals accepted jobs until they complete. The pool Puts a job before
+// it becomes visible to workers and Acks it once it has finished (whatever the
+// outcome), so Pending returns exactly the jobs that were lost in a crash.
+//
+// Delivery is at-least-once: a job that was running when the process died is
+// replayed, so job functions should be idempotent.
+type Queue[T any] interface {
+	// Put stores r and returns the ID assigned to it; r.ID is ignored.
+	Put(r Record[T]) (uint64, error)
+	// Ack removes the record with the given ID.
+	Ack(id uint64) error
+	// Pending returns the unacknowledged records in Put order.
+	Pending() ([]Record[T], error)
+	// Close releases the queue's resources.
+	Close() error
+}
+
+// Codec serializes payloads for persistent queues.
+type Codec[T any] interface {
+	Marshal(v T) ([]byte, error)
+	Unmarshal(data []byte, v *T) error
+}
+
+// JSONCodec encodes payloads with encoding/json.
+type JSONCodec[T any] struct{}
+
+func (JSONCodec[T]) Marshal(v T) ([]byte, error)       { return json.Marshal(v) }
+func (JSONCodec[T]) Unmarshal(data []byte, v *T) error { return json.Unmarshal(data, v) }
+
+// MemoryQueue is the default, non-durable Queue. It tracks in-flight records
+// in memory only, so nothing survives a restart.
+type MemoryQueue[T any] struct {
+	mu      sync.Mutex
+	nextID  uint64
+	pending map[uint64]Record[T]
+}
+
+// Ensure MemoryQueue satisfies Queue at compile time.
+var _ Queue[int] = (*MemoryQueue[int])(nil)
+
+func NewMemoryQueue[T any]() *MemoryQueue[T] {
+	return &MemoryQueue[T]{pending: make(map[uint64]Record[T])}
+}
+
+func (q *MemoryQueue[T]) Put(r Record[T]) (uint64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.nextID++
+	r.ID = q.nextID
+	q.pending[r.ID] = r
+	return r.ID, nil
+}
+
+func (q *MemoryQueue[T]) Ack(id uint64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.pending, id)
+	return nil
+}
+
+func (q *MemoryQueue[T]) Pending() ([]Record[T], error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	out := make([]Record[T], 0, len(q.pending))
+	for _, r := range q.pending {
+		out = append(out, r)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+func (q *MemoryQueue[T]) Close() error { return nil }
+
+// persist journals t unless it is recurring, already journaled or kept by
+// a RemoteQueue.
+func (p *Pool[T]) persist(t *task[T]) error {
+	if t.sched != nil || t.persisted || t.job.remote != nil {
+		return nil
+	}
+	id, err := p.journal.Put(Record[T]{
+		Payload:   t.job.Payload,
+		Priority:  t.job.Priority,
+		RunAt:     t.due,
+		Tenant:    t.job.Tenant,
+		Key:       t.job.Key,
+		SerialKey: t.job.SerialKey,
+	})
+	if err != nil {
+		return err
+	}
+	t.qid, t.persisted = id, true
+	return nil
+}
+
+// ack removes a finished job from the journal.
+func (p *Pool[T]) ack(t *task[T]) {
+	if !t.persisted {
+		return
+	}
+	if err := p.journal.Ack(t.qid); err != nil {
+		p.logger(t.job.Ctx).Error("Job ack failed", lg.Any("job_id", t.id), lg.Error("error", err))
+	}
+}
+
+// replay requeues the journal's pending records. They run with the pool's
+// Handler (or BatchFunc) and bypass the queue capacity so startup never blocks.
+func (p *Pool[T]) replay() {
+	logger := p.logger(context.Background())
+	records, err := p.journal.Pending()
+	if err != nil {
+		logger.Error("Job replay failed", lg.Error("error", err))
+		return
+	}
+	if len(records) == 0 {
+		return
+	}
+	if p.handler == nil && p.batchCfg.Fn == nil {
+		logger.Error("Job replay skipped: pool has no Handler", lg.Int("pending", len(records)))
+		return
+	}
+	now := time.Now()
+	for _, r := range records {
+		job := Job[T]{
+			Payload:   r.Payload,
+			Ctx:       context.Background(),
+			Priority:  r.Priority,
+			RunAt:     r.RunAt,
+			Tenant:    r.Tenant,
+			Key:       r.Key,
+			SerialKey: r.SerialKey,
+		}
+		if p.batchCfg.Fn == nil {
+			job.FnCtx = p.handler
+		}
+		t := p.newTask(job)
+		t.qid, t.persisted = r.ID, true
+		if r.Key != "" {
+			p.mu.Lock()
+			_, ok := p.claimKey(r.Key, t.handle)
+			p.mu.Unlock()
+			if !ok {
+				// the key was journaled twice; one replay is enough
+				p.ack(t)
+				continue
+			}
+		}
+		if r.RunAt.After(now) {
+			t.due = r.RunAt
+			p.delay(t)
+		} else {
+			p.enqueue(t)
+		}
+	}
+	logger.Info("Jobs replayed", lg.Int("count", len(records)))
+}
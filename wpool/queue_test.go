@@ -0,0 +1,270 @@
+package workerpool
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+type event struct {
+	Name string
+	N    int
+}
+
+func openTestQueue(t *testing.T, path string, cfg ...FileQueueConfig) *FileQueue[event] {
+	t.Helper()
+	q, err := OpenFileQueue[event](path, nil, cfg...)
+	if err != nil {
+		t.Fatalf("OpenFileQueue: %v", err)
+	}
+	return q
+}
+
+func TestFileQueueReplaysUnacked(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.log")
+	q := openTestQueue(t, path)
+
+	runAt := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+	id1, _ := q.Put(Record[event]{Payload: event{"a", 1}})
+	id2, _ := q.Put(Record[event]{Payload: event{"b", 2}, Priority: -3, RunAt: runAt})
+	id3, _ := q.Put(Record[event]{Payload: event{"c", 3}})
+	if err := q.Ack(id1); err != nil {
+		t.Fatalf("Ack: %v", err)
+	}
+	_ = q.Close()
+
+	q = openTestQueue(t, path)
+	defer q.Close()
+	recs, err := q.Pending()
+	if err != nil {
+		t.Fatalf("Pending: %v", err)
+	}
+	if len(recs) != 2 || recs[0].ID != id2 || recs[1].ID != id3 {
+		t.Fatalf("pending = %+v; want ids %d, %d", recs, id2, id3)
+	}
+	if recs[0].Payload != (event{"b", 2}) || recs[0].Priority != -3 || !recs[0].RunAt.Equal(runAt) {
+		t.Fatalf("record not restored: %+v", recs[0])
+	}
+	if id, _ := q.Put(Record[event]{}); id <= id3 {
+		t.Fatalf("new id %d reuses an old id (<= %d)", id, id3)
+	}
+}
+
+func TestFileQueueTruncatesTornTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.log")
+	q := openTestQueue(t, path)
+	_, _ = q.Put(Record[event]{Payload: event{"ok", 1}})
+	_ = q.Close()
+
+	// simulate a crash in the middle of appending an entry
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	_, _ = f.Write([]byte{0, 0, 0, 40, 1, 2})
+	_ = f.Close()
+
+	q = openTestQueue(t, path)
+	recs, _ := q.Pending()
+	if len(recs) != 1 || recs[0].Payload.Name != "ok" {
+		t.Fatalf("pending = %+v; want the intact record only", recs)
+	}
+	_, _ = q.Put(Record[event]{Payload: event{"after", 2}})
+	_ = q.Close()
+
+	q = openTestQueue(t, path)
+	defer q.Close()
+	if recs, _ := q.Pending(); len(recs) != 2 {
+		t.Fatalf("pending after reopen = %d; want 2", len(recs))
+	}
+}
+
+func TestFileQueueRejectsOversizedRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.log")
+	q := openTestQueue(t, path, FileQueueConfig{NoSync: true})
+	if _, err := q.Put(Record[event]{Payload: event{Name: string(make([]byte, maxEntrySize))}}); err == nil {
+		t.Fatal("oversized record accepted")
+	}
+	if _, err := q.Put(Record[event]{Payload: event{"ok", 1}}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	_ = q.Close()
+
+	q = openTestQueue(t, path)
+	defer q.Close()
+	if recs, _ := q.Pending(); len(recs) != 1 || recs[0].Payload.Name != "ok" {
+		t.Fatalf("pending = %d records; want the normal record only", len(recs))
+	}
+}
+
+func TestFileQueueFailedWriteClosesQueue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.log")
+	q := openTestQueue(t, path)
+	_, _ = q.Put(Record[event]{Payload: event{"ok", 1}})
+
+	// a read-only handle fails both the write and the rollback
+	ro, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.mu.Lock()
+	rw := q.f
+	q.f = ro
+	q.mu.Unlock()
+	defer rw.Close()
+	if _, err := q.Put(Record[event]{Payload: event{"lost", 2}}); err == nil {
+		t.Fatal("Put on a read-only log succeeded")
+	}
+	if _, err := q.Put(Record[event]{Payload: event{"late", 3}}); !errors.Is(err, ErrQueueClosed) {
+		t.Fatalf("Put after a failed rollback = %v; want ErrQueueClosed", err)
+	}
+
+	q = openTestQueue(t, path)
+	defer q.Close()
+	if recs, _ := q.Pending(); len(recs) != 1 || recs[0].Payload.Name != "ok" {
+		t.Fatalf("pending = %+v; want the first record only", recs)
+	}
+}
+
+func TestFileQueueCompacts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.log")
+	q := openTestQueue(t, path, FileQueueConfig{CompactAfter: 8, NoSync: true})
+
+	keep, _ := q.Put(Record[event]{Payload: event{"keep", 0}})
+	for i := 0; i < 20; i++ {
+		id, _ := q.Put(Record[event]{Payload: event{"tmp", i}})
+		_ = q.Ack(id)
+	}
+	st, _ := os.Stat(path)
+	_ = q.Close()
+
+	q = openTestQueue(t, path)
+	defer q.Close()
+	recs, _ := q.Pending()
+	if len(recs) != 1 || recs[0].ID != keep {
+		t.Fatalf("pending = %+v; want only id %d", recs, keep)
+	}
+	if st.Size() > 1024 {
+		t.Fatalf("log size = %d; want compacted", st.Size())
+	}
+}
+
+func TestPoolReplaysJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.log")
+	q := openTestQueue(t, path)
+	for i := 1; i <= 3; i++ {
+		_, _ = q.Put(Record[event]{Payload: event{"lost", i}})
+	}
+	_ = q.Close()
+
+	q = openTestQueue(t, path)
+	defer q.Close()
+	var mu sync.Mutex
+	var got []int
+	p := NewPool[event](2, fastRetry, Config[event]{
+		Queue: q,
+		Handler: func(e event) error {
+			mu.Lock()
+			got = append(got, e.N)
+			mu.Unlock()
+			return nil
+		},
+	})
+	_ = p.Submit(Job[event]{Payload: event{"new", 4}})
+	p.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 4 {
+		t.Fatalf("handled %v; want 4 jobs", got)
+	}
+	if recs, _ := q.Pending(); len(recs) != 0 {
+		t.Fatalf("pending after completion = %+v; want none", recs)
+	}
+}
+
+func TestFileQueueKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.log")
+	q := openTestQueue(t, path)
+	want := []Record[event]{
+		{Payload: event{"plain", 1}},
+		{Payload: event{"tenant", 2}, Tenant: "acme"},
+		{Payload: event{"keys", 3}, Key: "order-3", SerialKey: "customer-9"},
+		{Payload: event{"all", 4}, Tenant: "acme", SerialKey: "customer-9", Priority: -2},
+	}
+	for _, r := range want {
+		if _, err := q.Put(r); err != nil {
+			t.Fatalf("put: %v", err)
+		}
+	}
+	if _, err := q.Put(Record[event]{Key: string(make([]byte, 1<<16))}); err == nil {
+		t.Fatal("oversized key accepted")
+	}
+	_ = q.Close()
+
+	q = openTestQueue(t, path)
+	defer q.Close()
+	recs, err := q.Pending()
+	if err != nil || len(recs) != len(want) {
+		t.Fatalf("pending = %+v, %v", recs, err)
+	}
+	for i, r := range recs {
+		w := want[i]
+		if r.Payload != w.Payload || r.Tenant != w.Tenant || r.Key != w.Key || r.SerialKey != w.SerialKey || r.Priority != w.Priority {
+			t.Fatalf("record %d = %+v; want %+v", i, r, w)
+		}
+	}
+}
+
+func TestPoolReplayKeepsKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.log")
+	q := openTestQueue(t, path)
+	_, _ = q.Put(Record[event]{Payload: event{"first", 1}, Key: "k", SerialKey: "s"})
+	_, _ = q.Put(Record[event]{Payload: event{"second", 2}, SerialKey: "s"})
+	_, _ = q.Put(Record[event]{Payload: event{"again", 3}, Key: "k"})
+	_ = q.Close()
+
+	q = openTestQueue(t, path)
+	defer q.Close()
+	gate := make(chan struct{})
+	started := make(chan struct{})
+	var mu sync.Mutex
+	var got []int
+	p := NewPool[event](4, fastRetry, Config[event]{
+		Queue: q,
+		Handler: func(e event) error {
+			if e.N == 1 {
+				close(started)
+				<-gate
+			}
+			mu.Lock()
+			got = append(got, e.N)
+			mu.Unlock()
+			return nil
+		},
+	})
+	<-started
+	if err := p.Submit(Job[event]{Payload: event{"new", 4}, Key: "k"}); !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("submit with a replayed key: err = %v; want ErrDuplicate", err)
+	}
+	time.Sleep(20 * time.Millisecond) // the second job must wait for its key
+	close(gate)
+	p.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Fatalf("handled %v; want [1 2]", got)
+	}
+	if recs, _ := q.Pending(); len(recs) != 0 {
+		t.Fatalf("pending after completion = %+v; want none", recs)
+	}
+}
+
+func TestSubmitWithoutFnOrHandler(t *testing.T) {
+	p := NewPool[int](1, fastRetry)
+	defer p.Stop()
+	if err := p.Submit(Job[int]{Payload: 1}); err == nil {
+		t.Fatal("Submit without Fn succeeded; want error")
+	}
+}
@@ -0,0 +1,59 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterWait(t *testing.T) {
+	l := newRateLimiter(100, 2)
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		if err := l.wait(context.Background()); err != nil {
+			t.Fatalf("wait: %v", err)
+		}
+	}
+	// 2 burst tokens, then 4 more at 10ms each
+	if d := time.Since(start); d < 35*time.Millisecond {
+		t.Fatalf("6 waits took %v; want >= 40ms", d)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := newRateLimiter(0.001, 1).wait(ctx); err != nil {
+		t.Fatalf("first token should be free: %v", err)
+	}
+	l = newRateLimiter(0.001, 1)
+	_ = l.wait(context.Background())
+	if err := l.wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("wait err = %v; want canceled", err)
+	}
+}
+
+func TestPoolRateLimitIncludesRetries(t *testing.T) {
+	p := NewPool[int](4, fastRetry, Config[int]{RateLimit: 50})
+	defer p.Stop()
+
+	var calls atomic.Int32
+	start := time.Now()
+	h, _ := p.SubmitHandle(Job[int]{
+		Retry: &RetryPolicy{Attempts: 3, Initial: time.Millisecond, Max: time.Millisecond},
+		Fn: func(int) error {
+			if calls.Add(1) < 3 {
+				return errors.New("throttled")
+			}
+			return nil
+		},
+	})
+	res, _ := h.Wait(context.Background())
+	if res.Status != StatusSucceeded || res.Attempts != 3 {
+		t.Fatalf("result = %+v", res)
+	}
+	// three attempts at 50/s: at least two 20ms gaps
+	if d := time.Since(start); d < 35*time.Millisecond {
+		t.Fatalf("3 attempts took %v; want >= 40ms", d)
+	}
+}
@@ -0,0 +1,429 @@
+package workerpool
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultRedisKey        = "wpool"
+	defaultRedisVisibility = 30 * time.Second
+	defaultRedisPoll       = time.Second
+	defaultRedisTimeout    = 5 * time.Second
+	defaultRedisIdleConns  = 4
+	redisBodyField         = "body"
+)
+
+// RedisConfig holds optional RedisQueue settings.
+type RedisConfig struct {
+	// Stream is the key of the Redis stream holding the messages.
+	// Default "wpool".
+	Stream string
+	// Group is the consumer group shared by every replica. Default "wpool".
+	Group string
+	// Consumer names this queue within the group. Default host-pid-n.
+	Consumer string
+	// Visibility is how long a reserved message stays hidden before it is
+	// delivered again, unless it is acked or extended first. Nack delays are
+	// capped at it. Default 30s.
+	Visibility time.Duration
+	// Poll bounds how long Reserve blocks on the server before it looks
+	// again for messages whose visibility timeout or Nack delay has run
+	// out. Default 1s.
+	Poll time.Duration
+	// Password is sent with AUTH on every new connection when set.
+	Password string
+	// Timeout bounds dialing and each command round trip, not counting the
+	// time Reserve blocks on purpose. Default 5s.
+	Timeout time.Duration
+	// MaxIdleConns is the number of idle connections kept for reuse.
+	// Default 4.
+	MaxIdleConns int
+}
+
+// RedisQueue is a RemoteQueue on a Redis stream, shared through a consumer
+// group. Reserved messages sit in the group's pending list: its delivery
+// counter is the attempt count, and a message idle for longer than the
+// visibility timeout is claimed again by the next Reserve on any replica.
+// Nack sets the idle time so the message becomes claimable after the delay.
+// Needs Redis 6.2 or later for XAUTOCLAIM.
+type RedisQueue struct {
+	addr     string
+	cfg      RedisConfig
+	mu       sync.Mutex
+	idle     []*redisConn
+	isClosed bool
+	cursor   string // where the next XAUTOCLAIM scan starts
+}
+
+// Ensure RedisQueue satisfies RemoteQueue at compile time.
+var _ RemoteQueue = (*RedisQueue)(nil)
+
+var redisConsumers atomic.Uint64
+
+// DialRedisQueue connects to the Redis server at addr and creates the
+// stream and consumer group if they do not exist yet.
+func DialRedisQueue(addr string, config ...RedisConfig) (*RedisQueue, error) {
+	var cfg RedisConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Stream == "" {
+		cfg.Stream = defaultRedisKey
+	}
+	if cfg.Group == "" {
+		cfg.Group = defaultRedisKey
+	}
+	if cfg.Consumer == "" {
+		host, _ := os.Hostname()
+		cfg.Consumer = fmt.Sprintf("%s-%d-%d", host, os.Getpid(), redisConsumers.Add(1))
+	}
+	if cfg.Visibility <= 0 {
+		cfg.Visibility = defaultRedisVisibility
+	}
+	if cfg.Poll < time.Millisecond { // BLOCK 0 would wait forever
+		cfg.Poll = defaultRedisPoll
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultRedisTimeout
+	}
+	if cfg.MaxIdleConns <= 0 {
+		cfg.MaxIdleConns = defaultRedisIdleConns
+	}
+	q := &RedisQueue{addr: addr, cfg: cfg}
+	// start at 0 so messages pushed before the group existed are consumed
+	_, err := q.call(context.Background(), 0, "XGROUP", "CREATE", cfg.Stream, cfg.Group, "0", "MKSTREAM")
+	var re redisError
+	if err != nil && !(errors.As(err, &re) && strings.HasPrefix(string(re), "BUSYGROUP")) {
+		q.Close()
+		return nil, err
+	}
+	return q, nil
+}
+
+// Push appends body to the stream.
+func (q *RedisQueue) Push(ctx context.Context, body []byte) (string, error) {
+	v, err := q.call(ctx, 0, "XADD", q.cfg.Stream, "*", redisBodyField, string(body))
+	if err != nil {
+		return "", err
+	}
+	id, ok := v.(string)
+	if !ok {
+		return "", unexpectedReply("XADD", v)
+	}
+	return id, nil
+}
+
+// Reserve returns the oldest message whose visibility timeout has run out,
+// or else the next new message, waiting until one is available or ctx is
+// done.
+func (q *RedisQueue) Reserve(ctx context.Context) (Delivery, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return Delivery{}, err
+		}
+		d, ok, err := q.reclaim(ctx)
+		if err != nil || ok {
+			return d, err
+		}
+		d, ok, err = q.next(ctx)
+		if err != nil || ok {
+			return d, err
+		}
+	}
+}
+
+// reclaim claims one message that has been idle for the visibility timeout.
+// XAUTOCLAIM scans a bounded part of the pending list per call (COUNT*10
+// entries since Redis 7), so reclaim follows its cursor, kept across calls,
+// until the scan wraps around to the start.
+func (q *RedisQueue) reclaim(ctx context.Context) (Delivery, bool, error) {
+	q.mu.Lock()
+	cursor := q.cursor
+	q.mu.Unlock()
+	if cursor == "" {
+		cursor = "0-0"
+	}
+	for {
+		v, err := q.call(ctx, 0, "XAUTOCLAIM", q.cfg.Stream, q.cfg.Group, q.cfg.Consumer,
+			ms(q.cfg.Visibility), cursor, "COUNT", "1")
+		if err != nil {
+			return Delivery{}, false, err
+		}
+		reply, ok := v.([]any)
+		if !ok || len(reply) < 2 {
+			return Delivery{}, false, unexpectedReply("XAUTOCLAIM", v)
+		}
+		next, ok := reply[0].(string)
+		if !ok {
+			return Delivery{}, false, unexpectedReply("XAUTOCLAIM", v)
+		}
+		q.mu.Lock()
+		q.cursor = next
+		q.mu.Unlock()
+		entries, _ := reply[1].([]any)
+		for _, e := range entries {
+			d, ok := parseEntry(e)
+			if !ok {
+				continue // deleted while pending
+			}
+			// the claim counted as a delivery; read the counter back
+			v, err := q.call(ctx, 0, "XPENDING", q.cfg.Stream, q.cfg.Group, d.ID, d.ID, "1")
+			if err != nil {
+				return Delivery{}, false, err
+			}
+			rows, _ := v.([]any)
+			if len(rows) == 0 {
+				continue // acked in the meantime
+			}
+			row, _ := rows[0].([]any)
+			if len(row) < 4 {
+				return Delivery{}, false, unexpectedReply("XPENDING", v)
+			}
+			count, _ := row[3].(int64)
+			d.Attempt = int(count)
+			return d, true, nil
+		}
+		if next == "0-0" {
+			return Delivery{}, false, nil
+		}
+		cursor = next
+	}
+}
+
+// next reads one new message, blocking for up to the poll interval.
+func (q *RedisQueue) next(ctx context.Context) (Delivery, bool, error) {
+	v, err := q.call(ctx, q.cfg.Poll, "XREADGROUP", "GROUP", q.cfg.Group, q.cfg.Consumer,
+		"COUNT", "1", "BLOCK", ms(q.cfg.Poll), "STREAMS", q.cfg.Stream, ">")
+	if err != nil || v == nil {
+		return Delivery{}, false, err
+	}
+	streams, _ := v.([]any)
+	for _, s := range streams {
+		kv, _ := s.([]any)
+		if len(kv) < 2 {
+			return Delivery{}, false, unexpectedReply("XREADGROUP", v)
+		}
+		entries, _ := kv[1].([]any)
+		for _, e := range entries {
+			if d, ok := parseEntry(e); ok {
+				d.Attempt = 1
+				return d, true, nil
+			}
+		}
+	}
+	return Delivery{}, false, nil
+}
+
+// Ack acknowledges the message and deletes it from the stream.
+func (q *RedisQueue) Ack(ctx context.Context, id string) error {
+	if _, err := q.call(ctx, 0, "XACK", q.cfg.Stream, q.cfg.Group, id); err != nil {
+		return err
+	}
+	_, err := q.call(ctx, 0, "XDEL", q.cfg.Stream, id)
+	return err
+}
+
+// Nack leaves the message pending but backdates its idle time, so Reserve
+// claims it again once delay has passed.
+func (q *RedisQueue) Nack(ctx context.Context, id string, delay time.Duration) error {
+	idle := max(q.cfg.Visibility-delay, 0)
+	_, err := q.call(ctx, 0, "XCLAIM", q.cfg.Stream, q.cfg.Group, q.cfg.Consumer, "0", id,
+		"IDLE", ms(idle), "JUSTID")
+	return err
+}
+
+// Extend resets the message's idle time, restarting its visibility timeout.
+func (q *RedisQueue) Extend(ctx context.Context, id string) error {
+	_, err := q.call(ctx, 0, "XCLAIM", q.cfg.Stream, q.cfg.Group, q.cfg.Consumer, "0", id, "JUSTID")
+	return err
+}
+
+// Close closes the idle connections; connections in use are closed when
+// their command returns. Later calls fail with ErrQueueClosed.
+func (q *RedisQueue) Close() error {
+	q.mu.Lock()
+	idle := q.idle
+	q.idle, q.isClosed = nil, true
+	q.mu.Unlock()
+	for _, c := range idle {
+		c.nc.Close()
+	}
+	return nil
+}
+
+func ms(d time.Duration) string { return strconv.FormatInt(d.Milliseconds(), 10) }
+
+// parseEntry decodes a stream entry [id, [field, value, ...]].
+func parseEntry(e any) (Delivery, bool) {
+	kv, _ := e.([]any)
+	if len(kv) < 2 {
+		return Delivery{}, false
+	}
+	id, _ := kv[0].(string)
+	fields, _ := kv[1].([]any)
+	for i := 0; i+1 < len(fields); i += 2 {
+		if f, _ := fields[i].(string); f == redisBodyField {
+			body, _ := fields[i+1].(string)
+			return Delivery{ID: id, Body: []byte(body)}, id != ""
+		}
+	}
+	return Delivery{}, false
+}
+
+// call runs one command on a pooled connection. wait extends the I/O
+// deadline for commands that block on the server.
+func (q *RedisQueue) call(ctx context.Context, wait time.Duration, args ...string) (any, error) {
+	c, err := q.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.nc.SetDeadline(time.Now().Add(q.cfg.Timeout + wait))
+	stop := context.AfterFunc(ctx, func() { c.nc.SetDeadline(time.Unix(1, 0)) })
+	v, err := c.do(args...)
+	if !stop() {
+		c.nc.Close()
+		return nil, ctx.Err()
+	}
+	var re redisError
+	if err != nil && !errors.As(err, &re) {
+		c.nc.Close() // the connection may be mid-reply
+		return nil, fmt.Errorf("workerpool: redis %s: %w", args[0], err)
+	}
+	q.put(c)
+	if err != nil {
+		return nil, fmt.Errorf("workerpool: redis %s: %w", args[0], err)
+	}
+	return v, nil
+}
+
+// conn returns an idle connection or dials a new one.
+func (q *RedisQueue) conn(ctx context.Context) (*redisConn, error) {
+	q.mu.Lock()
+	if q.isClosed {
+		q.mu.Unlock()
+		return nil, ErrQueueClosed
+	}
+	if n := len(q.idle); n > 0 {
+		c := q.idle[n-1]
+		q.idle = q.idle[:n-1]
+		q.mu.Unlock()
+		return c, nil
+	}
+	q.mu.Unlock()
+
+	d := net.Dialer{Timeout: q.cfg.Timeout}
+	nc, err := d.DialContext(ctx, "tcp", q.addr)
+	if err != nil {
+		return nil, fmt.Errorf("workerpool: redis: %w", err)
+	}
+	c := &redisConn{nc: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc)}
+	if q.cfg.Password != "" {
+		nc.SetDeadline(time.Now().Add(q.cfg.Timeout))
+		if _, err := c.do("AUTH", q.cfg.Password); err != nil {
+			nc.Close()
+			return nil, fmt.Errorf("workerpool: redis AUTH: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// put returns c to the idle list, or closes it if the list is full.
+func (q *RedisQueue) put(c *redisConn) {
+	q.mu.Lock()
+	if !q.isClosed && len(q.idle) < q.cfg.MaxIdleConns {
+		q.idle = append(q.idle, c)
+		c = nil
+	}
+	q.mu.Unlock()
+	if c != nil {
+		c.nc.Close()
+	}
+}
+
+// redisError is an error reply from the server.
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+
+func unexpectedReply(cmd string, v any) error {
+	return fmt.Errorf("workerpool: redis %s: unexpected reply %v", cmd, v)
+}
+
+// redisConn speaks RESP2 on one connection. Replies decode to string (simple
+// and bulk strings), int64, []any, nil or a redisError.
+type redisConn struct {
+	nc net.Conn
+	r  *bufio.Reader
+	w  *bufio.Writer
+}
+
+func (c *redisConn) do(args ...string) (any, error) {
+	fmt.Fprintf(c.w, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(c.w, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if err := c.w.Flush(); err != nil {
+		return nil, err
+	}
+	v, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+	if re, ok := v.(redisError); ok {
+		return nil, re
+	}
+	return v, nil
+}
+
+func (c *redisConn) read() (any, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return redisError(line[1:]), nil
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		arr := make([]any, n)
+		for i := range arr {
+			if arr[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+		return arr, nil
+	}
+	return nil, fmt.Errorf("unknown reply type %q", line[0])
+}
@@ -0,0 +1,188 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/azargarov/go-utils/wpool/redistest"
+)
+
+func dialRedis(t *testing.T, s *redistest.Server, cfg RedisConfig) *RedisQueue {
+	t.Helper()
+	q, err := DialRedisQueue(s.Addr(), cfg)
+	if err != nil {
+		t.Fatalf("DialRedisQueue: %v", err)
+	}
+	t.Cleanup(func() { q.Close() })
+	return q
+}
+
+func reserve(t *testing.T, q *RedisQueue) Delivery {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	d, err := q.Reserve(ctx)
+	if err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	return d
+}
+
+func TestRedisQueue(t *testing.T) {
+	s := redistest.NewServer()
+	defer s.Close()
+	q := dialRedis(t, s, RedisConfig{Poll: 10 * time.Millisecond})
+	ctx := context.Background()
+
+	id1, err := q.Push(ctx, []byte("one"))
+	if err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	_, _ = q.Push(ctx, []byte("two"))
+
+	d := reserve(t, q)
+	if d.ID != id1 || string(d.Body) != "one" || d.Attempt != 1 {
+		t.Fatalf("first delivery = %+v", d)
+	}
+	if err := q.Ack(ctx, d.ID); err != nil {
+		t.Fatalf("Ack: %v", err)
+	}
+
+	d = reserve(t, q)
+	if string(d.Body) != "two" || d.Attempt != 1 {
+		t.Fatalf("second delivery = %+v", d)
+	}
+	if err := q.Nack(ctx, d.ID, 0); err != nil {
+		t.Fatalf("Nack: %v", err)
+	}
+	again := reserve(t, q)
+	if again.ID != d.ID || string(again.Body) != "two" || again.Attempt != 2 {
+		t.Fatalf("redelivery = %+v; want attempt 2 of %s", again, d.ID)
+	}
+	_ = q.Ack(ctx, again.ID)
+
+	short, cancel := context.WithTimeout(ctx, 30*time.Millisecond)
+	defer cancel()
+	if _, err := q.Reserve(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Reserve on an empty queue: %v", err)
+	}
+}
+
+func TestRedisQueueVisibility(t *testing.T) {
+	s := redistest.NewServer()
+	defer s.Close()
+	cfg := RedisConfig{Visibility: 80 * time.Millisecond, Poll: 10 * time.Millisecond}
+	a, b := dialRedis(t, s, cfg), dialRedis(t, s, cfg)
+	ctx := context.Background()
+	_, _ = a.Push(ctx, []byte("job"))
+
+	lost := reserve(t, a) // a "crashes" without acking
+	start := time.Now()
+	d := reserve(t, b)
+	if d.ID != lost.ID || d.Attempt != 2 {
+		t.Fatalf("redelivery = %+v; want attempt 2 of %s", d, lost.ID)
+	}
+	if waited := time.Since(start); waited < 60*time.Millisecond {
+		t.Fatalf("redelivered after %v; want the visibility timeout", waited)
+	}
+
+	// Extend keeps it hidden past the original timeout
+	time.Sleep(50 * time.Millisecond)
+	if err := b.Extend(ctx, d.ID); err != nil {
+		t.Fatalf("Extend: %v", err)
+	}
+	short, cancel := context.WithTimeout(ctx, 60*time.Millisecond)
+	defer cancel()
+	if got, err := a.Reserve(short); err == nil {
+		t.Fatalf("extended message delivered again: %+v", got)
+	}
+}
+
+func TestRedisQueueNackDelay(t *testing.T) {
+	s := redistest.NewServer()
+	defer s.Close()
+	q := dialRedis(t, s, RedisConfig{Visibility: time.Minute, Poll: 10 * time.Millisecond})
+	ctx := context.Background()
+	_, _ = q.Push(ctx, []byte("job"))
+
+	d := reserve(t, q)
+	start := time.Now()
+	if err := q.Nack(ctx, d.ID, 60*time.Millisecond); err != nil {
+		t.Fatalf("Nack: %v", err)
+	}
+	again := reserve(t, q)
+	if waited := time.Since(start); waited < 50*time.Millisecond || waited > 30*time.Second {
+		t.Fatalf("redelivered after %v; want the 60ms delay", waited)
+	}
+	if again.Attempt != 2 {
+		t.Fatalf("Attempt = %d; want 2", again.Attempt)
+	}
+}
+
+func TestRedisQueueReclaimsBehindBusyEntries(t *testing.T) {
+	s := redistest.NewServer()
+	defer s.Close()
+	cfg := RedisConfig{Visibility: time.Minute, Poll: 10 * time.Millisecond}
+	a, b := dialRedis(t, s, cfg), dialRedis(t, s, cfg)
+	ctx := context.Background()
+
+	// more busy entries ahead of the idle one than one XAUTOCLAIM scans
+	const busy = 25
+	for i := 0; i <= busy; i++ {
+		_, _ = a.Push(ctx, []byte("job"))
+	}
+	var last Delivery
+	for i := 0; i <= busy; i++ {
+		last = reserve(t, a)
+	}
+	if err := a.Nack(ctx, last.ID, 0); err != nil {
+		t.Fatalf("Nack: %v", err)
+	}
+	for i := 0; i < 2; i++ { // and again once the cursor has moved past it
+		d := reserve(t, b)
+		if d.ID != last.ID || d.Attempt != 2+i {
+			t.Fatalf("delivery %d = %+v; want attempt %d of %s", i, d, 2+i, last.ID)
+		}
+		_ = b.Nack(ctx, d.ID, 0)
+	}
+}
+
+func TestRedisQueueReconnect(t *testing.T) {
+	s := redistest.NewServer()
+	defer s.Close()
+	q := dialRedis(t, s, RedisConfig{})
+	ctx := context.Background()
+	if _, err := q.Push(ctx, []byte("a")); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	s.CloseClientConnections()
+	var err error
+	for i := 0; i < 2; i++ { // the pooled connection is dead; the next one is fresh
+		if _, err = q.Push(ctx, []byte("b")); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		t.Fatalf("Push after the connection dropped: %v", err)
+	}
+
+	_ = q.Close()
+	if _, err := q.Push(ctx, []byte("c")); !errors.Is(err, ErrQueueClosed) {
+		t.Fatalf("Push after Close: %v; want ErrQueueClosed", err)
+	}
+}
+
+func TestRedisQueuePassword(t *testing.T) {
+	s := redistest.NewServer()
+	defer s.Close()
+	s.RequirePassword("secret")
+	if _, err := DialRedisQueue(s.Addr(), RedisConfig{Password: "wrong"}); err == nil {
+		t.Fatal("dial with a wrong password succeeded")
+	}
+	q := dialRedis(t, s, RedisConfig{Password: "secret"})
+	if _, err := q.Push(context.Background(), []byte("a")); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+}
@@ -0,0 +1,802 @@
+// Package redistest provides an in-process server that speaks enough of the
+// Redis protocol to exercise workerpool.RedisQueue in tests, in the spirit of
+// net/http/httptest. It keeps everything in memory and implements only the
+// stream commands the queue uses: XGROUP CREATE, XADD, XREADGROUP, XAUTOCLAIM,
+// XCLAIM, XPENDING, XACK, XDEL and XLEN, plus PING, AUTH and QUIT.
+package redistest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server is a fake Redis server listening on a local port.
+type Server struct {
+	ln       net.Listener
+	password string
+
+	mu      sync.Mutex
+	streams map[string]*stream
+	changed chan struct{} // closed and replaced whenever an entry is added
+	conns   map[net.Conn]struct{}
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+// NewServer starts a server on 127.0.0.1 with a random port. It panics if
+// it cannot listen, like httptest.NewServer.
+func NewServer() *Server {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("redistest: failed to listen: %v", err))
+	}
+	s := &Server{
+		ln:      ln,
+		streams: make(map[string]*stream),
+		changed: make(chan struct{}),
+		conns:   make(map[net.Conn]struct{}),
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s
+}
+
+// RequirePassword makes the server reject commands other than AUTH, PING
+// and QUIT until a connection authenticates with password.
+func (s *Server) RequirePassword(password string) {
+	s.mu.Lock()
+	s.password = password
+	s.mu.Unlock()
+}
+
+// Addr returns the host:port the server listens on.
+func (s *Server) Addr() string { return s.ln.Addr().String() }
+
+// CloseClientConnections closes every open client connection, simulating a
+// network failure. The server keeps its data and accepts new connections.
+func (s *Server) CloseClientConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		c.Close()
+	}
+}
+
+// Close stops the server and closes every client connection.
+func (s *Server) Close() {
+	s.mu.Lock()
+	s.closed = true
+	s.ln.Close()
+	for c := range s.conns {
+		c.Close()
+	}
+	s.broadcast()
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		c, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			c.Close()
+			return
+		}
+		s.conns[c] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+		go s.handle(c)
+	}
+}
+
+// broadcast wakes blocked readers. Callers hold s.mu.
+func (s *Server) broadcast() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *Server) handle(c net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		c.Close()
+	}()
+	r := bufio.NewReader(c)
+	w := bufio.NewWriter(c)
+	authed := false
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				writeReply(w, replyError("ERR Protocol error: "+err.Error()))
+				w.Flush()
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+		cmd := strings.ToUpper(args[0])
+		var reply any
+		s.mu.Lock()
+		locked := s.password != "" && !authed
+		password := s.password
+		s.mu.Unlock()
+		switch {
+		case cmd == "QUIT":
+			writeReply(w, status("OK"))
+			w.Flush()
+			return
+		case cmd == "AUTH":
+			switch {
+			case len(args) != 2:
+				reply = errArgs(cmd)
+			case password == "":
+				reply = replyError("ERR AUTH <password> called without any password configured for the default user")
+			case args[1] != password:
+				reply = replyError("WRONGPASS invalid username-password pair or user is disabled.")
+			default:
+				authed = true
+				reply = status("OK")
+			}
+		case cmd == "PING":
+			reply = status("PONG")
+		case locked:
+			reply = replyError("NOAUTH Authentication required.")
+		default:
+			reply = s.exec(cmd, args[1:])
+		}
+		writeReply(w, reply)
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// exec runs one data command.
+func (s *Server) exec(cmd string, args []string) any {
+	switch cmd {
+	case "XGROUP":
+		return s.xgroup(args)
+	case "XADD":
+		return s.xadd(args)
+	case "XLEN":
+		return s.xlen(args)
+	case "XREADGROUP":
+		return s.xreadgroup(args)
+	case "XACK":
+		return s.xack(args)
+	case "XDEL":
+		return s.xdel(args)
+	case "XCLAIM":
+		return s.xclaim(args)
+	case "XAUTOCLAIM":
+		return s.xautoclaim(args)
+	case "XPENDING":
+		return s.xpending(args)
+	}
+	return replyError(fmt.Sprintf("ERR unknown command '%s'", strings.ToLower(cmd)))
+}
+
+// Reply values: status and replyError are simple strings, string is a bulk
+// string, int64 an integer, []any an array, nil a null bulk string and
+// nilArray a null array.
+type (
+	status     string
+	replyError string
+	nilArray   struct{}
+)
+
+func (e replyError) Error() string { return string(e) }
+
+func errArgs(cmd string) replyError {
+	return replyError(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(cmd)))
+}
+
+const errSyntax = replyError("ERR syntax error")
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return strings.Fields(line), nil // inline command
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid multibulk length")
+	}
+	args := make([]string, n)
+	for i := range args {
+		line, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(line, "$") {
+			return nil, fmt.Errorf("expected '$', got '%.1s'", line)
+		}
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("invalid bulk length")
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func writeReply(w *bufio.Writer, v any) {
+	switch v := v.(type) {
+	case status:
+		fmt.Fprintf(w, "+%s\r\n", string(v))
+	case replyError:
+		fmt.Fprintf(w, "-%s\r\n", string(v))
+	case int64:
+		fmt.Fprintf(w, ":%d\r\n", v)
+	case string:
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v), v)
+	case nil:
+		w.WriteString("$-1\r\n")
+	case nilArray:
+		w.WriteString("*-1\r\n")
+	case []any:
+		fmt.Fprintf(w, "*%d\r\n", len(v))
+		for _, e := range v {
+			writeReply(w, e)
+		}
+	default:
+		panic(fmt.Sprintf("redistest: unexpected reply %T", v))
+	}
+}
+
+// streamID is the ms-seq ID of a stream entry.
+type streamID struct{ ms, seq uint64 }
+
+func (id streamID) String() string { return fmt.Sprintf("%d-%d", id.ms, id.seq) }
+
+func (id streamID) less(o streamID) bool {
+	return id.ms < o.ms || (id.ms == o.ms && id.seq < o.seq)
+}
+
+// parseID parses an explicit ID, "-" or "+". A bare ms stands for ms-0, or
+// ms-max as the end of a range when end is set.
+func parseID(s string, end bool) (streamID, error) {
+	switch s {
+	case "-":
+		return streamID{}, nil
+	case "+":
+		return streamID{^uint64(0), ^uint64(0)}, nil
+	}
+	msPart, seqPart, hasSeq := strings.Cut(s, "-")
+	ms, err := strconv.ParseUint(msPart, 10, 64)
+	if err != nil {
+		return streamID{}, replyError("ERR Invalid stream ID specified as stream command argument")
+	}
+	if !hasSeq {
+		if end {
+			return streamID{ms, ^uint64(0)}, nil
+		}
+		return streamID{ms, 0}, nil
+	}
+	seq, err := strconv.ParseUint(seqPart, 10, 64)
+	if err != nil {
+		return streamID{}, replyError("ERR Invalid stream ID specified as stream command argument")
+	}
+	return streamID{ms, seq}, nil
+}
+
+type entry struct {
+	id     streamID
+	fields []string
+}
+
+type pending struct {
+	consumer  string
+	delivered time.Time
+	count     int64
+}
+
+type group struct {
+	last streamID
+	pel  map[streamID]*pending
+}
+
+type stream struct {
+	entries []entry // in ID order
+	last    streamID
+	groups  map[string]*group
+}
+
+func (st *stream) find(id streamID) (entry, bool) {
+	i := sort.Search(len(st.entries), func(i int) bool { return !st.entries[i].id.less(id) })
+	if i < len(st.entries) && st.entries[i].id == id {
+		return st.entries[i], true
+	}
+	return entry{}, false
+}
+
+func (e entry) reply() any {
+	fields := make([]any, len(e.fields))
+	for i, f := range e.fields {
+		fields[i] = f
+	}
+	return []any{e.id.String(), fields}
+}
+
+// pendingIDs returns the IDs in g's pending list from start on, in order.
+func (g *group) pendingIDs(start, end streamID) []streamID {
+	var ids []streamID
+	for id := range g.pel {
+		if !id.less(start) && !end.less(id) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].less(ids[j]) })
+	return ids
+}
+
+// lookup returns the group of key; callers hold s.mu.
+func (s *Server) lookup(key, name, cmd string) (*stream, *group, any) {
+	st, ok := s.streams[key]
+	if ok {
+		if g, ok := st.groups[name]; ok {
+			return st, g, nil
+		}
+	}
+	return nil, nil, replyError(fmt.Sprintf("NOGROUP No such key '%s' or consumer group '%s' in %s command", key, name, cmd))
+}
+
+func (s *Server) xgroup(args []string) any {
+	if len(args) < 4 || strings.ToUpper(args[0]) != "CREATE" {
+		return replyError("ERR unknown subcommand or wrong number of arguments for 'xgroup' command")
+	}
+	key, name, start := args[1], args[2], args[3]
+	mkstream := len(args) > 4 && strings.ToUpper(args[4]) == "MKSTREAM"
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.streams[key]
+	if !ok {
+		if !mkstream {
+			return replyError("ERR The XGROUP subcommand requires the key to exist. Note that for CREATE you may want to use the MKSTREAM option to create an empty stream automatically.")
+		}
+		st = &stream{groups: make(map[string]*group)}
+		s.streams[key] = st
+	}
+	if _, ok := st.groups[name]; ok {
+		return replyError("BUSYGROUP Consumer Group name already exists")
+	}
+	last := st.last
+	if start != "$" {
+		id, err := parseID(start, false)
+		if err != nil {
+			return err
+		}
+		last = id
+	}
+	st.groups[name] = &group{last: last, pel: make(map[streamID]*pending)}
+	return status("OK")
+}
+
+func (s *Server) xadd(args []string) any {
+	if len(args) < 4 || len(args)%2 != 0 {
+		return errArgs("xadd")
+	}
+	key, idArg := args[0], args[1]
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.streams[key]
+	if !ok {
+		st = &stream{groups: make(map[string]*group)}
+	}
+	var id streamID
+	if idArg == "*" {
+		id = streamID{ms: uint64(time.Now().UnixMilli())}
+		if !st.last.less(id) {
+			id = streamID{st.last.ms, st.last.seq + 1}
+		}
+	} else {
+		parsed, err := parseID(idArg, false)
+		if err != nil {
+			return err
+		}
+		if !st.last.less(parsed) {
+			return replyError("ERR The ID specified in XADD is equal or smaller than the target stream top item")
+		}
+		id = parsed
+	}
+	s.streams[key] = st
+	st.entries = append(st.entries, entry{id: id, fields: append([]string(nil), args[2:]...)})
+	st.last = id
+	s.broadcast()
+	return id.String()
+}
+
+func (s *Server) xlen(args []string) any {
+	if len(args) != 1 {
+		return errArgs("xlen")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if st, ok := s.streams[args[0]]; ok {
+		return int64(len(st.entries))
+	}
+	return int64(0)
+}
+
+func (s *Server) xreadgroup(args []string) any {
+	if len(args) < 6 || strings.ToUpper(args[0]) != "GROUP" {
+		return errSyntax
+	}
+	name, consumer := args[1], args[2]
+	count, block, blocking := 0, time.Duration(0), false
+	i := 3
+	for ; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "COUNT", "BLOCK":
+			if i+1 >= len(args) {
+				return errSyntax
+			}
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil || n < 0 {
+				return replyError("ERR value is not an integer or out of range")
+			}
+			if strings.ToUpper(args[i]) == "COUNT" {
+				count = n
+			} else {
+				block, blocking = time.Duration(n)*time.Millisecond, true
+			}
+			i++
+			continue
+		case "NOACK":
+			continue
+		case "STREAMS":
+		default:
+			return errSyntax
+		}
+		break
+	}
+	rest := args[min(i+1, len(args)):]
+	if len(rest) != 2 {
+		return replyError("ERR this fake server supports XREADGROUP on a single stream")
+	}
+	key, from := rest[0], rest[1]
+
+	var deadline <-chan time.Time
+	if blocking && block > 0 {
+		timer := time.NewTimer(block)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	for {
+		s.mu.Lock()
+		st, g, errReply := s.lookup(key, name, "XREADGROUP")
+		if errReply != nil {
+			s.mu.Unlock()
+			return errReply
+		}
+		var out []any
+		if from == ">" {
+			for _, e := range st.entries {
+				if count > 0 && len(out) == count {
+					break
+				}
+				if !g.last.less(e.id) {
+					continue
+				}
+				g.last = e.id
+				g.pel[e.id] = &pending{consumer: consumer, delivered: time.Now(), count: 1}
+				out = append(out, e.reply())
+			}
+		} else {
+			// the consumer's own pending history after from
+			start, err := parseID(from, false)
+			if err != nil {
+				s.mu.Unlock()
+				return err
+			}
+			for _, id := range g.pendingIDs(start, streamID{^uint64(0), ^uint64(0)}) {
+				if count > 0 && len(out) == count {
+					break
+				}
+				if id == start || g.pel[id].consumer != consumer {
+					continue
+				}
+				if e, ok := st.find(id); ok {
+					out = append(out, e.reply())
+				}
+			}
+			s.mu.Unlock()
+			return []any{[]any{key, out}}
+		}
+		changed, closed := s.changed, s.closed
+		s.mu.Unlock()
+		if len(out) > 0 {
+			return []any{[]any{key, out}}
+		}
+		if !blocking || closed {
+			return nilArray{}
+		}
+		select {
+		case <-changed:
+		case <-deadline:
+			return nilArray{}
+		}
+	}
+}
+
+func (s *Server) xack(args []string) any {
+	if len(args) < 3 {
+		return errArgs("xack")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.streams[args[0]]
+	if !ok {
+		return int64(0)
+	}
+	g, ok := st.groups[args[1]]
+	if !ok {
+		return int64(0)
+	}
+	var n int64
+	for _, a := range args[2:] {
+		id, err := parseID(a, false)
+		if err != nil {
+			return err
+		}
+		if _, ok := g.pel[id]; ok {
+			delete(g.pel, id)
+			n++
+		}
+	}
+	return n
+}
+
+func (s *Server) xdel(args []string) any {
+	if len(args) < 2 {
+		return errArgs("xdel")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.streams[args[0]]
+	if !ok {
+		return int64(0)
+	}
+	var n int64
+	for _, a := range args[1:] {
+		id, err := parseID(a, false)
+		if err != nil {
+			return err
+		}
+		i := sort.Search(len(st.entries), func(i int) bool { return !st.entries[i].id.less(id) })
+		if i < len(st.entries) && st.entries[i].id == id {
+			st.entries = append(st.entries[:i], st.entries[i+1:]...)
+			n++
+		}
+	}
+	return n
+}
+
+// claim moves a pending entry to consumer. Without justID it counts as a
+// new delivery.
+func (p *pending) claim(consumer string, idle time.Duration, justID bool) {
+	p.consumer = consumer
+	p.delivered = time.Now().Add(-idle)
+	if !justID {
+		p.count++
+	}
+}
+
+func (s *Server) xclaim(args []string) any {
+	if len(args) < 5 {
+		return errArgs("xclaim")
+	}
+	key, name, consumer := args[0], args[1], args[2]
+	minIdle, err := strconv.ParseInt(args[3], 10, 64)
+	if err != nil {
+		return replyError("ERR Invalid min-idle-time argument for XCLAIM")
+	}
+	var ids []streamID
+	var idle time.Duration
+	justID := false
+	for i := 4; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "IDLE":
+			if i+1 >= len(args) {
+				return errSyntax
+			}
+			ms, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil || ms < 0 {
+				return replyError("ERR Invalid IDLE option argument for XCLAIM")
+			}
+			idle = time.Duration(ms) * time.Millisecond
+			i++
+		case "JUSTID":
+			justID = true
+		default:
+			id, err := parseID(args[i], false)
+			if err != nil {
+				return err
+			}
+			ids = append(ids, id)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, g, errReply := s.lookup(key, name, "XCLAIM")
+	if errReply != nil {
+		return errReply
+	}
+	out := []any{}
+	for _, id := range ids {
+		p, ok := g.pel[id]
+		if !ok || time.Since(p.delivered) < time.Duration(minIdle)*time.Millisecond {
+			continue
+		}
+		e, ok := st.find(id)
+		if !ok {
+			delete(g.pel, id)
+			continue
+		}
+		p.claim(consumer, idle, justID)
+		if justID {
+			out = append(out, id.String())
+		} else {
+			out = append(out, e.reply())
+		}
+	}
+	return out
+}
+
+func (s *Server) xautoclaim(args []string) any {
+	if len(args) < 5 {
+		return errArgs("xautoclaim")
+	}
+	key, name, consumer := args[0], args[1], args[2]
+	minIdle, err := strconv.ParseInt(args[3], 10, 64)
+	if err != nil {
+		return replyError("ERR Invalid min-idle-time argument for XAUTOCLAIM")
+	}
+	start, errID := parseID(args[4], false)
+	if errID != nil {
+		return errID
+	}
+	count, justID := 100, false
+	for i := 5; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "COUNT":
+			if i+1 >= len(args) {
+				return errSyntax
+			}
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil || n < 1 {
+				return replyError("ERR COUNT must be > 0")
+			}
+			count = n
+			i++
+		case "JUSTID":
+			justID = true
+		default:
+			return errSyntax
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, g, errReply := s.lookup(key, name, "XAUTOCLAIM")
+	if errReply != nil {
+		return errReply
+	}
+	claimed, deleted := []any{}, []any{}
+	next := streamID{}
+	ids := g.pendingIDs(start, streamID{^uint64(0), ^uint64(0)})
+	// like Redis 7, scan at most COUNT*10 entries per call
+	i := 0
+	for attempts := count * 10; i < len(ids) && attempts > 0 && len(claimed) < count; i, attempts = i+1, attempts-1 {
+		id := ids[i]
+		p := g.pel[id]
+		if time.Since(p.delivered) < time.Duration(minIdle)*time.Millisecond {
+			continue
+		}
+		e, ok := st.find(id)
+		if !ok {
+			delete(g.pel, id)
+			deleted = append(deleted, id.String())
+			continue
+		}
+		p.claim(consumer, 0, justID)
+		if justID {
+			claimed = append(claimed, id.String())
+		} else {
+			claimed = append(claimed, e.reply())
+		}
+	}
+	if i < len(ids) {
+		next = ids[i]
+	}
+	return []any{next.String(), claimed, deleted}
+}
+
+// xpending implements the extended form of XPENDING:
+// key group [IDLE min-idle] start end count [consumer].
+func (s *Server) xpending(args []string) any {
+	if len(args) < 5 {
+		return replyError("ERR this fake server supports only the extended form of XPENDING")
+	}
+	key, name := args[0], args[1]
+	rest := args[2:]
+	var minIdle time.Duration
+	if strings.ToUpper(rest[0]) == "IDLE" {
+		if len(rest) < 5 {
+			return errSyntax
+		}
+		ms, err := strconv.ParseInt(rest[1], 10, 64)
+		if err != nil {
+			return errSyntax
+		}
+		minIdle = time.Duration(ms) * time.Millisecond
+		rest = rest[2:]
+	}
+	start, err := parseID(rest[0], false)
+	if err != nil {
+		return err
+	}
+	end, err := parseID(rest[1], true)
+	if err != nil {
+		return err
+	}
+	count, convErr := strconv.Atoi(rest[2])
+	if convErr != nil {
+		return replyError("ERR value is not an integer or out of range")
+	}
+	consumer := ""
+	if len(rest) > 3 {
+		consumer = rest[3]
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, g, errReply := s.lookup(key, name, "XPENDING")
+	if errReply != nil {
+		return errReply
+	}
+	out := []any{}
+	for _, id := range g.pendingIDs(start, end) {
+		if len(out) == count {
+			break
+		}
+		p := g.pel[id]
+		idle := time.Since(p.delivered)
+		if idle < minIdle || (consumer != "" && p.consumer != consumer) {
+			continue
+		}
+		out = append(out, []any{id.String(), p.consumer, idle.Milliseconds(), p.count})
+	}
+	return out
+}
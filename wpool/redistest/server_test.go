@@ -0,0 +1,136 @@
+package redistest
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// roundTrip sends one command and returns the raw reply up to the first
+// line break, or the whole reply for arrays of simple values.
+func roundTrip(t *testing.T, c net.Conn, r *bufio.Reader, args ...string) string {
+	t.Helper()
+	fmt.Fprintf(c, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(c, "$%d\r\n%s\r\n", len(a), a)
+	}
+	c.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err := r.ReadString('\n')
+	if err != nil {
+		t.Fatalf("%s: %v", args[0], err)
+	}
+	return strings.TrimSuffix(line, "\r\n")
+}
+
+func TestServer(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	c, err := net.Dial("tcp", s.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	r := bufio.NewReader(c)
+
+	if got := roundTrip(t, c, r, "PING"); got != "+PONG" {
+		t.Fatalf("PING = %q", got)
+	}
+	if got := roundTrip(t, c, r, "XGROUP", "CREATE", "jobs", "g", "0"); !strings.HasPrefix(got, "-ERR") {
+		t.Fatalf("XGROUP CREATE without MKSTREAM = %q", got)
+	}
+	if got := roundTrip(t, c, r, "XGROUP", "CREATE", "jobs", "g", "0", "MKSTREAM"); got != "+OK" {
+		t.Fatalf("XGROUP CREATE = %q", got)
+	}
+	if got := roundTrip(t, c, r, "XGROUP", "CREATE", "jobs", "g", "0", "MKSTREAM"); !strings.HasPrefix(got, "-BUSYGROUP") {
+		t.Fatalf("second XGROUP CREATE = %q", got)
+	}
+	if got := roundTrip(t, c, r, "XADD", "jobs", "5-1", "body", "x"); got != "$3" {
+		t.Fatalf("XADD = %q", got)
+	}
+	r.ReadString('\n') // the ID
+	if got := roundTrip(t, c, r, "XADD", "jobs", "5-1", "body", "y"); !strings.HasPrefix(got, "-ERR") {
+		t.Fatalf("XADD with a stale ID = %q", got)
+	}
+	if got := roundTrip(t, c, r, "XLEN", "jobs"); got != ":1" {
+		t.Fatalf("XLEN = %q", got)
+	}
+
+	// a blocked read returns a null array on timeout
+	start := time.Now()
+	if got := roundTrip(t, c, r, "XREADGROUP", "GROUP", "g", "c", "COUNT", "5", "BLOCK", "30", "STREAMS", "jobs", ">"); got != "*1" {
+		t.Fatalf("XREADGROUP = %q", got)
+	}
+	for i := 0; i < 12; i++ { // the rest of [[jobs, [[5-1, [body, x]]]]]
+		r.ReadString('\n')
+	}
+	if got := roundTrip(t, c, r, "XREADGROUP", "GROUP", "g", "c", "BLOCK", "30", "STREAMS", "jobs", ">"); got != "*-1" {
+		t.Fatalf("empty XREADGROUP = %q", got)
+	}
+	if time.Since(start) < 30*time.Millisecond {
+		t.Fatal("XREADGROUP did not block")
+	}
+	if got := roundTrip(t, c, r, "XACK", "jobs", "g", "5-1", "9-9"); got != ":1" {
+		t.Fatalf("XACK = %q", got)
+	}
+	if got := roundTrip(t, c, r, "NOPE"); !strings.HasPrefix(got, "-ERR unknown command") {
+		t.Fatalf("unknown command = %q", got)
+	}
+}
+
+func TestServerBlockingReadWakesOnAdd(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	dial := func() (net.Conn, *bufio.Reader) {
+		c, err := net.Dial("tcp", s.Addr())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { c.Close() })
+		return c, bufio.NewReader(c)
+	}
+	reader, rr := dial()
+	writer, wr := dial()
+	roundTrip(t, writer, wr, "XGROUP", "CREATE", "jobs", "g", "$", "MKSTREAM")
+
+	got := make(chan string, 1)
+	go func() {
+		got <- roundTrip(t, reader, rr, "XREADGROUP", "GROUP", "g", "c", "BLOCK", "0", "STREAMS", "jobs", ">")
+	}()
+	time.Sleep(20 * time.Millisecond)
+	roundTrip(t, writer, wr, "XADD", "jobs", "*", "body", "x")
+	select {
+	case line := <-got:
+		if line != "*1" {
+			t.Fatalf("XREADGROUP = %q", line)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("blocked XREADGROUP was not woken by XADD")
+	}
+}
+
+func TestServerPassword(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.RequirePassword("secret")
+	c, err := net.Dial("tcp", s.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	r := bufio.NewReader(c)
+	if got := roundTrip(t, c, r, "XLEN", "jobs"); !strings.HasPrefix(got, "-NOAUTH") {
+		t.Fatalf("XLEN before AUTH = %q", got)
+	}
+	if got := roundTrip(t, c, r, "AUTH", "wrong"); !strings.HasPrefix(got, "-WRONGPASS") {
+		t.Fatalf("AUTH wrong = %q", got)
+	}
+	if got := roundTrip(t, c, r, "AUTH", "secret"); got != "+OK" {
+		t.Fatalf("AUTH = %q", got)
+	}
+	if got := roundTrip(t, c, r, "XLEN", "jobs"); got != ":0" {
+		t.Fatalf("XLEN after AUTH = %q", got)
+	}
+}
@@ -0,0 +1,199 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	lg "github.com/azargarov/go-utils/zlog"
+)
+
+// RemoteQueue is a work queue kept in an external backend and shared by the
+// pools of several processes, typically one per replica. A reserved message
+// stays hidden from other consumers for the backend's visibility timeout; if
+// it is neither acked nor extended by then it is delivered again, so a
+// replica that dies mid-job does not lose it. The backend counts deliveries,
+// so a job's attempts carry over from one replica to the next.
+//
+// Delivery is at-least-once: job functions should be idempotent.
+type RemoteQueue interface {
+	// Push appends a message and returns the ID the backend assigned to it.
+	Push(ctx context.Context, body []byte) (string, error)
+	// Reserve waits until a message is visible, hides it and returns it.
+	Reserve(ctx context.Context) (Delivery, error)
+	// Ack removes a delivered message for good.
+	Ack(ctx context.Context, id string) error
+	// Nack makes a delivered message visible again after delay.
+	Nack(ctx context.Context, id string, delay time.Duration) error
+	// Extend restarts the visibility timeout of a delivered message.
+	Extend(ctx context.Context, id string) error
+	// Close releases the queue's resources.
+	Close() error
+}
+
+// Delivery is a message reserved from a RemoteQueue.
+type Delivery struct {
+	ID      string
+	Body    []byte
+	Attempt int // deliveries of the message so far, including this one
+}
+
+// RemoteConfig holds optional Remote settings.
+type RemoteConfig[T any] struct {
+	// Codec encodes payloads into message bodies. Default JSONCodec.
+	Codec Codec[T]
+	// Prefetch caps the messages this replica holds reserved at once,
+	// queued or running. Default the pool's maximum worker count.
+	Prefetch int
+	// Heartbeat extends the visibility timeout of a running job this often,
+	// for jobs that may outlast it. Zero never extends.
+	Heartbeat time.Duration
+}
+
+// Remote feeds a Pool from a RemoteQueue. Each delivery runs one attempt on
+// the pool with its Handler; the retry policy then decides, using the
+// attempt count kept by the backend, whether the message is nacked with
+// the policy's backoff or given up on. Given up and succeeded messages are
+// acked; failed ones also go to Config.DeadLetter as usual.
+type Remote[T any] struct {
+	pool      *Pool[T]
+	q         RemoteQueue
+	codec     Codec[T]
+	prefetch  int
+	heartbeat time.Duration
+}
+
+// remoteRun links a job to the delivery it came from.
+type remoteRun struct {
+	attempt int           // backend delivery count of this run
+	delay   time.Duration // backoff before the retry; set before requeue closes
+	requeue chan struct{} // closed instead of the handle when the attempt may be retried
+}
+
+// NewRemote connects p to q. It does not consume until Run is called.
+func NewRemote[T any](p *Pool[T], q RemoteQueue, config ...RemoteConfig[T]) *Remote[T] {
+	var cfg RemoteConfig[T]
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Codec == nil {
+		cfg.Codec = JSONCodec[T]{}
+	}
+	if cfg.Prefetch <= 0 {
+		cfg.Prefetch = int(p.maxWorkers.Load())
+	}
+	return &Remote[T]{pool: p, q: q, codec: cfg.Codec, prefetch: cfg.Prefetch, heartbeat: cfg.Heartbeat}
+}
+
+// Submit encodes payload and pushes it to the remote queue, from where the
+// Run of any replica may pick it up.
+func (r *Remote[T]) Submit(ctx context.Context, payload T) error {
+	body, err := r.codec.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = r.q.Push(ctx, body)
+	return err
+}
+
+// Run reserves messages and runs them on the pool until ctx is done or the
+// queue is closed, then waits for the jobs it started to be acked or
+// nacked. Other backend errors are logged and retried with backoff. Run
+// needs the pool to have a Handler, and the pool must outlive it.
+func (r *Remote[T]) Run(ctx context.Context) error {
+	if r.pool.handler == nil {
+		return errors.New("workerpool: remote queue needs a pool Handler")
+	}
+	logger := r.pool.logger(ctx)
+	slots := make(chan struct{}, r.prefetch)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	boff := defaultBackoff(defaultInitialRetry, defauiltMaxRetry)
+	for {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		d, err := r.q.Reserve(ctx)
+		if err != nil {
+			<-slots
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if errors.Is(err, ErrQueueClosed) {
+				return err
+			}
+			delay := boff.Next()
+			logger.Error("Remote reserve failed", lg.Error("error", err), lg.String("retry_in", delay.String()))
+			pause := time.NewTimer(delay)
+			select {
+			case <-pause.C:
+			case <-ctx.Done():
+				pause.Stop()
+				return ctx.Err()
+			}
+			continue
+		}
+		boff = defaultBackoff(defaultInitialRetry, defauiltMaxRetry)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			r.process(ctx, d)
+		}()
+	}
+}
+
+// process runs one delivery on the pool and settles it with the backend.
+func (r *Remote[T]) process(ctx context.Context, d Delivery) {
+	logger := r.pool.logger(ctx)
+	var payload T
+	if err := r.codec.Unmarshal(d.Body, &payload); err != nil {
+		// redelivering cannot help
+		logger.Error("Remote message dropped: cannot decode", lg.String("message_id", d.ID), lg.Error("error", err))
+		r.settle(logger, d.ID, r.q.Ack(context.Background(), d.ID))
+		return
+	}
+	run := &remoteRun{attempt: max(d.Attempt, 1), requeue: make(chan struct{})}
+	job := Job[T]{Payload: payload, FnCtx: r.pool.handler, remote: run}
+	h, err := r.pool.submit(ctx, &job, true)
+	if err != nil {
+		r.settle(logger, d.ID, r.q.Nack(context.Background(), d.ID, 0))
+		return
+	}
+
+	var tick <-chan time.Time
+	if r.heartbeat > 0 {
+		ticker := time.NewTicker(r.heartbeat)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-h.Done():
+			res, _ := h.Result()
+			if res.Status == StatusSucceeded || res.Status == StatusFailed {
+				r.settle(logger, d.ID, r.q.Ack(context.Background(), d.ID))
+			} else {
+				// canceled, dropped or aborted: let another replica have it
+				r.settle(logger, d.ID, r.q.Nack(context.Background(), d.ID, 0))
+			}
+			return
+		case <-run.requeue:
+			r.settle(logger, d.ID, r.q.Nack(context.Background(), d.ID, run.delay))
+			return
+		case <-tick:
+			r.settle(logger, d.ID, r.q.Extend(context.Background(), d.ID))
+		}
+	}
+}
+
+// settle logs a failed Ack, Nack or Extend; the backend redelivers the
+// message once its visibility timeout runs out.
+func (r *Remote[T]) settle(logger lg.ZLogger, id string, err error) {
+	if err != nil {
+		logger.Error("Remote queue update failed", lg.String("message_id", id), lg.Error("error", err))
+	}
+}
@@ -0,0 +1,257 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/azargarov/go-utils/wpool/redistest"
+)
+
+// replica is one process of a distributed pool: a pool, its connection to
+// the shared queue and the Remote consuming it.
+type replica struct {
+	pool   *Pool[int]
+	remote *Remote[int]
+	cancel context.CancelFunc
+	done   chan error
+}
+
+func startReplica(t *testing.T, s *redistest.Server, qcfg RedisConfig, cfg Config[int]) *replica {
+	t.Helper()
+	q := dialRedis(t, s, qcfg)
+	p := NewPool[int](2, RetryPolicy{Attempts: 3, Initial: time.Millisecond, Max: 2 * time.Millisecond}, cfg)
+	r := &replica{pool: p, remote: NewRemote(p, RemoteQueue(q)), done: make(chan error, 1)}
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	go func() { r.done <- r.remote.Run(ctx) }()
+	t.Cleanup(r.stop)
+	return r
+}
+
+func (r *replica) stop() {
+	r.cancel()
+	<-r.done
+	r.done <- nil // let stop be called again
+	r.pool.Stop()
+}
+
+func TestRemoteSharedQueue(t *testing.T) {
+	s := redistest.NewServer()
+	defer s.Close()
+	qcfg := RedisConfig{Poll: 10 * time.Millisecond}
+
+	var mu sync.Mutex
+	seen := make(map[int]int)
+	handler := func(n int) error {
+		mu.Lock()
+		seen[n]++
+		mu.Unlock()
+		return nil
+	}
+	results := make(chan Result[int], 50)
+	a := startReplica(t, s, qcfg, Config[int]{Handler: handler, Results: results})
+	b := startReplica(t, s, qcfg, Config[int]{Handler: handler, Results: results})
+
+	for i := 0; i < 50; i++ {
+		if err := a.remote.Submit(context.Background(), i); err != nil {
+			t.Fatalf("Submit: %v", err)
+		}
+	}
+	for i := 0; i < 50; i++ {
+		select {
+		case res := <-results:
+			if res.Status != StatusSucceeded || res.Attempts != 1 {
+				t.Fatalf("result = %+v", res)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only %d of 50 jobs finished", i)
+		}
+	}
+	a.stop()
+	b.stop()
+	mu.Lock()
+	defer mu.Unlock()
+	for i := 0; i < 50; i++ {
+		if seen[i] != 1 {
+			t.Fatalf("job %d ran %d times", i, seen[i])
+		}
+	}
+	if n := a.pool.Stats().Succeeded + b.pool.Stats().Succeeded; n != 50 {
+		t.Fatalf("succeeded %d; want 50", n)
+	}
+}
+
+func TestRemoteRetriesAcrossReplicas(t *testing.T) {
+	s := redistest.NewServer()
+	defer s.Close()
+	qcfg := RedisConfig{Poll: 5 * time.Millisecond}
+
+	var mu sync.Mutex
+	calls := 0
+	boom := errors.New("boom")
+	handler := func(int) error {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		return boom
+	}
+	dls := make(chan DeadLetter[int], 1)
+	cfg := Config[int]{Handler: handler, DeadLetter: DeadLetterChan[int](dls)}
+	a := startReplica(t, s, qcfg, cfg)
+	b := startReplica(t, s, qcfg, cfg)
+
+	if err := a.remote.Submit(context.Background(), 7); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	select {
+	case dl := <-dls:
+		if dl.Payload != 7 || !errors.Is(dl.Err, boom) {
+			t.Fatalf("dead letter = %+v", dl)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("job was not dead-lettered")
+	}
+	a.stop()
+	b.stop()
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 3 {
+		t.Fatalf("handler ran %d times; want the policy's 3 attempts", calls)
+	}
+	sa, sb := a.pool.Stats(), b.pool.Stats()
+	if r := sa.Retries + sb.Retries; r != 2 {
+		t.Fatalf("retries = %d; want 2", r)
+	}
+	// attempts handed back to the queue are neither canceled nor final
+	if c, f := sa.Canceled+sb.Canceled, sa.Failed+sb.Failed; c != 0 || f != 1 {
+		t.Fatalf("canceled = %d, failed = %d; want 0 and 1", c, f)
+	}
+
+	// the message was acked once it was given up on
+	q := dialRedis(t, s, qcfg)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if d, err := q.Reserve(ctx); err == nil {
+		t.Fatalf("dead-lettered message still queued: %+v", d)
+	}
+}
+
+func TestRemoteRedeliversAfterCrash(t *testing.T) {
+	s := redistest.NewServer()
+	defer s.Close()
+	qcfg := RedisConfig{Visibility: 50 * time.Millisecond, Poll: 5 * time.Millisecond}
+
+	crashed := dialRedis(t, s, qcfg)
+	_, _ = crashed.Push(context.Background(), []byte("42"))
+	if d := reserve(t, crashed); d.Attempt != 1 {
+		t.Fatalf("first delivery = %+v", d)
+	}
+
+	results := make(chan Result[int], 1)
+	startReplica(t, s, qcfg, Config[int]{
+		Handler: func(int) error { return nil },
+		Results: results,
+	})
+	select {
+	case res := <-results:
+		if res.Payload != 42 || res.Status != StatusSucceeded || res.Attempts != 2 {
+			t.Fatalf("result = %+v; want success on attempt 2", res)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("message was not redelivered")
+	}
+}
+
+func TestRemoteHeartbeat(t *testing.T) {
+	s := redistest.NewServer()
+	defer s.Close()
+	qcfg := RedisConfig{Visibility: 60 * time.Millisecond, Poll: 5 * time.Millisecond}
+
+	q := dialRedis(t, s, qcfg)
+	p := NewPool[int](1, fastRetry, Config[int]{Handler: func(int) error {
+		time.Sleep(200 * time.Millisecond) // outlasts the visibility timeout
+		return nil
+	}})
+	defer p.Stop()
+	r := NewRemote(p, RemoteQueue(q), RemoteConfig[int]{Heartbeat: 20 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- r.Run(ctx) }()
+	_ = r.Submit(context.Background(), 1)
+
+	// another replica must not get the running job
+	other := dialRedis(t, s, qcfg)
+	short, cancelShort := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancelShort()
+	if d, err := other.Reserve(short); err == nil {
+		t.Fatalf("running job delivered again: %+v", d)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run = %v", err)
+	}
+	if s := p.Stats(); s.Succeeded != 1 {
+		t.Fatalf("succeeded %d; want 1 (Run waits for its jobs)", s.Succeeded)
+	}
+}
+
+func TestRemoteUndecodableMessage(t *testing.T) {
+	s := redistest.NewServer()
+	defer s.Close()
+	qcfg := RedisConfig{Poll: 5 * time.Millisecond}
+	q := dialRedis(t, s, qcfg)
+	_, _ = q.Push(context.Background(), []byte("not json"))
+	_, _ = q.Push(context.Background(), []byte(fmt.Sprint(5)))
+
+	results := make(chan Result[int], 2)
+	startReplica(t, s, qcfg, Config[int]{Handler: func(int) error { return nil }, Results: results})
+	select {
+	case res := <-results:
+		if res.Payload != 5 {
+			t.Fatalf("result = %+v", res)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("valid message after a bad one did not run")
+	}
+}
+
+func TestRemoteNeedsHandler(t *testing.T) {
+	p := NewPool[int](1, fastRetry)
+	defer p.Stop()
+	if err := NewRemote(p, RemoteQueue(nil)).Run(context.Background()); err == nil {
+		t.Fatal("Run without a Handler succeeded")
+	}
+}
+
+func TestRemoteRetryLeavesHandleOpen(t *testing.T) {
+	p := NewPool[int](1, RetryPolicy{Attempts: 3, Initial: time.Millisecond, Max: time.Millisecond},
+		Config[int]{Handler: func(int) error { return errors.New("boom") }})
+	defer p.Stop()
+
+	run := &remoteRun{attempt: 1, requeue: make(chan struct{})}
+	job := Job[int]{Payload: 1, FnCtx: p.handler, remote: run}
+	h, err := p.submit(context.Background(), &job, true)
+	if err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	select {
+	case <-run.requeue:
+	case <-h.Done():
+		t.Fatal("handle completed on a retryable attempt")
+	case <-time.After(time.Second):
+		t.Fatal("attempt was not requeued")
+	}
+	if h.Status() != StatusRetrying || h.Status().Done() || run.delay <= 0 {
+		t.Fatalf("status %v, delay %v", h.Status(), run.delay)
+	}
+	if _, ok := h.Result(); ok {
+		t.Fatal("handle has a result")
+	}
+	if s := p.Stats(); s.Canceled != 0 || s.Retries != 1 {
+		t.Fatalf("stats = %+v", s)
+	}
+}
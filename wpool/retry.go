@@ -0,0 +1,129 @@
+package workerpool
+
+import (
+	"errors"
+	"time"
+
+	boff "github.com/azargarov/go-utils/backoff"
+)
+
+// BackoffFunc creates the delay sequence for one run of a job.
+type BackoffFunc func(initial, max time.Duration) boff.Strategy
+
+// RetryRule overrides the retry policy for errors it matches. Zero fields
+// inherit from the enclosing RetryPolicy.
+type RetryRule struct {
+	Match func(error) bool
+	// Attempts caps the total number of attempts while the last error
+	// matches; 1 means matching errors are never retried.
+	Attempts int
+	Initial  time.Duration
+	Max      time.Duration
+	Backoff  BackoffFunc
+}
+
+// ErrorIs matches errors that wrap target, as reported by errors.Is.
+func ErrorIs(target error) func(error) bool {
+	return func(err error) bool { return errors.Is(err, target) }
+}
+
+// ErrorAs matches errors that wrap an E, as reported by errors.As.
+func ErrorAs[E error]() func(error) bool {
+	return func(err error) bool {
+		var e E
+		return errors.As(err, &e)
+	}
+}
+
+func defaultBackoff(initial, max time.Duration) boff.Strategy {
+	return boff.New(initial, max, time.Now().UnixNano())
+}
+
+// merge overrides pol with the non-zero fields of o.
+func (pol RetryPolicy) merge(o *RetryPolicy) RetryPolicy {
+	if o == nil {
+		return pol
+	}
+	if o.Attempts > 0 {
+		pol.Attempts = o.Attempts
+	}
+	if o.Initial > 0 {
+		pol.Initial = o.Initial
+	}
+	if o.Max > 0 {
+		pol.Max = o.Max
+	}
+	if o.Timeout > 0 {
+		pol.Timeout = o.Timeout
+	}
+	if o.IsRetryable != nil {
+		pol.IsRetryable = o.IsRetryable
+	}
+	if o.Backoff != nil {
+		pol.Backoff = o.Backoff
+	}
+	if o.Rules != nil {
+		pol.Rules = o.Rules
+	}
+	return pol
+}
+
+// retrier tracks the backoff sequences of one job run. Each rule keeps its
+// own sequence, so interleaved error kinds do not reset each other.
+type retrier struct {
+	pol  RetryPolicy
+	seqs map[int]boff.Strategy
+	rule int // index of the rule that matched the last error; -1 for none
+}
+
+func newRetrier(pol RetryPolicy) *retrier {
+	return &retrier{pol: pol, seqs: make(map[int]boff.Strategy)}
+}
+
+// retry reports whether the job may run again after attempt failed with err.
+func (r *retrier) retry(attempt int, err error) bool {
+	if r.pol.IsRetryable != nil && !r.pol.IsRetryable(err) {
+		return false
+	}
+	r.rule = -1
+	limit := r.pol.Attempts
+	for i, rule := range r.pol.Rules {
+		if rule.Match != nil && rule.Match(err) {
+			r.rule = i
+			if rule.Attempts > 0 {
+				limit = rule.Attempts
+			}
+			break
+		}
+	}
+	return attempt < limit
+}
+
+// delay returns the next backoff for the error last passed to retry.
+func (r *retrier) delay() time.Duration {
+	seq, ok := r.seqs[r.rule]
+	if !ok {
+		initial, max, newSeq := r.pol.Initial, r.pol.Max, r.pol.Backoff
+		if r.rule >= 0 {
+			rule := r.pol.Rules[r.rule]
+			if rule.Initial > 0 {
+				initial = rule.Initial
+			}
+			if rule.Max > 0 {
+				max = rule.Max
+			}
+			if rule.Backoff != nil {
+				newSeq = rule.Backoff
+			}
+		}
+		if max < initial {
+			max = initial
+		}
+		if newSeq == nil {
+			newSeq = defaultBackoff
+		}
+		seq = newSeq(initial, max)
+		r.seqs[r.rule] = seq
+	}
+	return seq.Next()
+}
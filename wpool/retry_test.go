@@ -0,0 +1,84 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	boff "github.com/azargarov/go-utils/backoff"
+)
+
+type rateLimitError struct{}
+
+func (e *rateLimitError) Error() string { return "rate limited" }
+
+var errInvalid = errors.New("invalid payload")
+
+func TestRetrierRules(t *testing.T) {
+	r := newRetrier(RetryPolicy{
+		Attempts: 3,
+		Initial:  time.Millisecond,
+		Max:      time.Millisecond,
+		Rules: []RetryRule{
+			{Match: ErrorIs(errInvalid), Attempts: 1},
+			{Match: ErrorAs[*rateLimitError](), Attempts: 10, Backoff: func(time.Duration, time.Duration) boff.Strategy {
+				return boff.Constant(time.Second)
+			}},
+		},
+	})
+
+	if r.retry(1, errInvalid) {
+		t.Fatal("validation error was retried")
+	}
+	if !r.retry(5, &rateLimitError{}) {
+		t.Fatal("rate limit error not retried within its own attempt budget")
+	}
+	if d := r.delay(); d != time.Second {
+		t.Fatalf("rate limit delay = %v; want 1s", d)
+	}
+	if !r.retry(1, errors.New("other")) || r.retry(3, errors.New("other")) {
+		t.Fatal("other errors must use the policy's 3 attempts")
+	}
+	if d := r.delay(); d > time.Millisecond {
+		t.Fatalf("default delay = %v; want <= 1ms", d)
+	}
+}
+
+func TestRetrierIsRetryable(t *testing.T) {
+	r := newRetrier(RetryPolicy{Attempts: 5, IsRetryable: func(err error) bool { return !errors.Is(err, errInvalid) }})
+	if r.retry(1, errInvalid) {
+		t.Fatal("IsRetryable=false error was retried")
+	}
+	if !r.retry(1, errors.New("transient")) {
+		t.Fatal("retryable error was not retried")
+	}
+}
+
+func TestPoolNeverRetriesClassifiedErrors(t *testing.T) {
+	dls := make(chan DeadLetter[int], 1)
+	p := NewPool[int](1, fastRetry, Config[int]{DeadLetter: DeadLetterChan[int](dls)})
+	defer p.Stop()
+
+	var calls atomic.Int32
+	h, _ := p.SubmitHandle(Job[int]{
+		Retry: &RetryPolicy{Attempts: 5, Rules: []RetryRule{{Match: ErrorIs(errInvalid), Attempts: 1}}},
+		Fn: func(int) error {
+			calls.Add(1)
+			return errInvalid
+		},
+	})
+	res, _ := h.Wait(context.Background())
+	if res.Status != StatusFailed || res.Attempts != 1 || calls.Load() != 1 {
+		t.Fatalf("result = %+v, calls = %d; want one failed attempt", res, calls.Load())
+	}
+	select {
+	case dl := <-dls:
+		if !errors.Is(dl.Err, errInvalid) {
+			t.Fatalf("dead letter err = %v", dl.Err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("non-retryable failure was not dead-lettered")
+	}
+}
@@ -0,0 +1,332 @@
+package workerpool
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule yields the activation times of a recurring job.
+// Next returns the first activation strictly after t, or the zero time if
+// there are no more activations.
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+type every time.Duration
+
+// Every returns a fixed-rate Schedule firing every d. A non-positive d yields
+// no activations.
+func Every(d time.Duration) Schedule { return every(d) }
+
+func (e every) Next(t time.Time) time.Time {
+	if e <= 0 {
+		return time.Time{}
+	}
+	return t.Add(time.Duration(e))
+}
+
+// SubmitAfter queues job to run once delay has elapsed. It does not block:
+// delayed jobs do not count against the queue capacity until they are due.
+func (p *Pool[T]) SubmitAfter(delay time.Duration, job Job[T]) error {
+	job.RunAt = time.Now().Add(delay)
+	return p.Submit(job)
+}
+
+// Schedule runs job at every activation of s, starting at job.RunAt if set or
+// at the first activation after now. Runs never overlap: the next activation
+// is armed once the current run finishes, skipping activations that were
+// missed meanwhile. The schedule ends when job.Ctx is canceled or the pool
+// shuts down.
+func (p *Pool[T]) Schedule(s Schedule, job Job[T]) error {
+	if s == nil {
+		return errors.New("workerpool: nil schedule")
+	}
+	if err := p.prepare(&job); err != nil {
+		return err
+	}
+	due := job.RunAt
+	if due.IsZero() {
+		due = s.Next(time.Now())
+	}
+	if due.IsZero() {
+		return errors.New("workerpool: schedule has no activations")
+	}
+	t := &task[T]{job: job, id: p.nextID.Add(1), due: due, sched: s}
+	if !p.delay(t) {
+		return ErrPoolClosed
+	}
+	p.submitted(t)
+	return nil
+}
+
+// delay parks t in the delayed heap until t.due. Cancelling job.Ctx removes it
+// right away. It reports false if the pool is closed.
+func (p *Pool[T]) delay(t *task[T]) bool {
+	p.mu.Lock()
+	if p.isClosed {
+		p.mu.Unlock()
+		return false
+	}
+	p.track(t)
+	p.seq++
+	t.seq = p.seq
+	t.waiting = true
+	heap.Push(&p.delayed, t)
+	if t.stopCancel == nil && t.job.Ctx.Done() != nil {
+		t.stopCancel = context.AfterFunc(t.job.Ctx, func() { p.cancelDelayed(t) })
+	}
+	p.mu.Unlock()
+	p.wake()
+	return true
+}
+
+// promoteDue moves due jobs to the scheduler and reports how long until the
+// next delayed job is due, if any. Callers hold p.mu.
+func (p *Pool[T]) promoteDue(now time.Time) (time.Duration, bool) {
+	for p.delayed.Len() > 0 {
+		t := p.delayed.items[0]
+		if t.due.After(now) {
+			return t.due.Sub(now), true
+		}
+		heap.Pop(&p.delayed)
+		t.waiting = false
+		if t.sched == nil && t.stopCancel != nil {
+			t.stopCancel()
+		}
+		t.enqueued = now
+		p.queue.push(t)
+	}
+	return 0, false
+}
+
+// dropDelayed empties the delayed heap. Callers hold p.mu.
+func (p *Pool[T]) dropDelayed() []*task[T] {
+	dropped := p.delayed.items
+	for _, t := range dropped {
+		t.waiting = false
+		t.index = -1
+	}
+	p.delayed.items = nil
+	return dropped
+}
+
+// cancelDelayed removes t from the delayed heap once its context is done.
+func (p *Pool[T]) cancelDelayed(t *task[T]) {
+	p.mu.Lock()
+	if !t.waiting {
+		p.mu.Unlock()
+		return
+	}
+	heap.Remove(&p.delayed, t.index)
+	t.waiting = false
+	p.mu.Unlock()
+	p.wake()
+	p.discard(t, true)
+}
+
+// discard finalizes a waiting job that will not run. Jobs dropped by
+// Shutdown or ShutdownNow stay in the journal so they are replayed on the
+// next start.
+func (p *Pool[T]) discard(t *task[T], ack bool) {
+	if t.stopCancel != nil {
+		t.stopCancel()
+	}
+	p.jobLog(t.job.Ctx, t).log(LogDiscard, "Job discarded")
+	if ack {
+		p.ack(t)
+	}
+	if t.job.CleanupFunc != nil {
+		t.job.CleanupFunc()
+	}
+	err := t.job.Ctx.Err()
+	if err == nil {
+		err = ErrPoolClosed
+	}
+	p.forget(t) // ends a recurring job's registration too
+	p.finish(t, Result[T]{Status: StatusCanceled, Err: err})
+}
+
+// rearm schedules the next activation of a recurring job after a run.
+func (p *Pool[T]) rearm(t *task[T]) {
+	now := time.Now()
+	next := t.sched.Next(t.due)
+	if !next.IsZero() && !next.After(now) {
+		next = t.sched.Next(now)
+	}
+	if next.IsZero() || t.job.Ctx.Err() != nil {
+		if t.stopCancel != nil {
+			t.stopCancel()
+		}
+		p.forget(t)
+		return
+	}
+	t.due = next
+	if !p.delay(t) {
+		if t.stopCancel != nil {
+			t.stopCancel()
+		}
+		p.forget(t)
+	}
+}
+
+// cronSchedule is a parsed five-field cron expression.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+type cronBounds struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	cronMinute = cronBounds{0, 59, nil}
+	cronHour   = cronBounds{0, 23, nil}
+	cronDom    = cronBounds{1, 31, nil}
+	cronMonth  = cronBounds{1, 12, map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	cronDow = cronBounds{0, 7, map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+	cronDescriptors = map[string]string{
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+		"@monthly":  "0 0 1 * *",
+		"@weekly":   "0 0 * * 0",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@hourly":   "0 * * * *",
+	}
+)
+
+// ParseCron parses a standard five-field cron expression
+// ("minute hour day-of-month month day-of-week") into a Schedule.
+// Fields accept *, lists, ranges and steps (e.g. "*/15", "1-5", "mon,wed").
+// The @hourly, @daily, @weekly, @monthly and @yearly shorthands are also
+// recognized. Activations are computed in the location of the time passed
+// to Next.
+func ParseCron(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if d, ok := cronDescriptors[strings.ToLower(spec)]; ok {
+		spec = d
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("workerpool: cron %q: want 5 fields, got %d", spec, len(fields))
+	}
+	var c cronSchedule
+	var err error
+	if c.minute, err = parseCronField(fields[0], cronMinute); err != nil {
+		return nil, err
+	}
+	if c.hour, err = parseCronField(fields[1], cronHour); err != nil {
+		return nil, err
+	}
+	if c.dom, err = parseCronField(fields[2], cronDom); err != nil {
+		return nil, err
+	}
+	if c.month, err = parseCronField(fields[3], cronMonth); err != nil {
+		return nil, err
+	}
+	if c.dow, err = parseCronField(fields[4], cronDow); err != nil {
+		return nil, err
+	}
+	if c.dow&(1<<7) != 0 { // 7 is an alias for Sunday
+		c.dow |= 1
+	}
+	c.domAny = strings.HasPrefix(fields[2], "*") || fields[2] == "?"
+	c.dowAny = strings.HasPrefix(fields[4], "*") || fields[4] == "?"
+	return &c, nil
+}
+
+func parseCronField(field string, b cronBounds) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("workerpool: cron field %q: bad step", field)
+			}
+			step = n
+		}
+		lo, hi := b.min, b.max
+		if rng != "*" && rng != "?" {
+			loStr, hiStr, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = b.value(loStr); err != nil {
+				return 0, fmt.Errorf("workerpool: cron field %q: %w", field, err)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = b.value(hiStr); err != nil {
+					return 0, fmt.Errorf("workerpool: cron field %q: %w", field, err)
+				}
+			} else if hasStep {
+				hi = b.max
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("workerpool: cron field %q: empty range", field)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func (b cronBounds) value(s string) (int, error) {
+	if v, ok := b.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("bad value %q", s)
+	}
+	if v < b.min || v > b.max {
+		return 0, fmt.Errorf("value %d out of range [%d, %d]", v, b.min, b.max)
+	}
+	return v, nil
+}
+
+// dayMatches follows cron semantics: when both day fields are restricted,
+// a day matching either of them is an activation.
+func (c *cronSchedule) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+func (c *cronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Year() + 5
+	for t.Year() <= limit {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, loc)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
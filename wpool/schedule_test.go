@@ -0,0 +1,146 @@
+package workerpool
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSubmitAfterDelaysJob(t *testing.T) {
+	p := NewPool[int](1, fastRetry)
+	defer p.Stop()
+
+	start := time.Now()
+	ran := make(chan time.Time, 1)
+	err := p.SubmitAfter(50*time.Millisecond, Job[int]{Fn: func(int) error {
+		ran <- time.Now()
+		return nil
+	}})
+	if err != nil {
+		t.Fatalf("SubmitAfter: %v", err)
+	}
+	if got := p.QueueLength(); got != 0 {
+		t.Fatalf("queue length = %d; want 0 while the job is not due", got)
+	}
+
+	select {
+	case at := <-ran:
+		if at.Sub(start) < 50*time.Millisecond {
+			t.Fatalf("job ran after %v; want >= 50ms", at.Sub(start))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("delayed job did not run")
+	}
+}
+
+func TestDelayedJobCanceledBeforeDue(t *testing.T) {
+	p := NewPool[int](1, fastRetry)
+	defer p.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var ran atomic.Bool
+	cleaned := make(chan struct{})
+	_ = p.Submit(Job[int]{
+		Ctx:         ctx,
+		RunAt:       time.Now().Add(time.Hour),
+		Fn:          func(int) error { ran.Store(true); return nil },
+		CleanupFunc: func() { close(cleaned) },
+	})
+	cancel()
+
+	select {
+	case <-cleaned:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("cleanup not called for canceled delayed job")
+	}
+	if ran.Load() {
+		t.Fatal("canceled delayed job ran")
+	}
+}
+
+func TestScheduleEveryUntilCanceled(t *testing.T) {
+	p := NewPool[int](2, fastRetry)
+	defer p.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var runs atomic.Int32
+	err := p.Schedule(Every(10*time.Millisecond), Job[int]{Ctx: ctx, Fn: func(int) error {
+		if runs.Add(1) == 3 {
+			cancel()
+		}
+		return nil
+	}})
+	if err != nil {
+		t.Fatalf("Schedule: %v", err)
+	}
+
+	deadline := time.After(time.Second)
+	for runs.Load() < 3 {
+		select {
+		case <-deadline:
+			t.Fatalf("runs = %d; want 3", runs.Load())
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := runs.Load(); got != 3 {
+		t.Fatalf("runs after cancel = %d; want 3", got)
+	}
+}
+
+func TestShutdownDiscardsPendingDelayedJobs(t *testing.T) {
+	p := NewPool[int](1, fastRetry)
+
+	cleaned := make(chan struct{})
+	_ = p.SubmitAfter(time.Hour, Job[int]{
+		Fn:          func(int) error { t.Error("job should not run"); return nil },
+		CleanupFunc: func() { close(cleaned) },
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := p.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	select {
+	case <-cleaned:
+	default:
+		t.Fatal("cleanup not called for discarded delayed job")
+	}
+	if err := p.Schedule(Every(time.Second), Job[int]{Fn: func(int) error { return nil }}); err == nil {
+		t.Fatal("Schedule succeeded on closed pool; want error")
+	}
+}
+
+func TestParseCronNext(t *testing.T) {
+	base := time.Date(2025, time.January, 31, 10, 7, 30, 0, time.UTC)
+	cases := []struct {
+		spec string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2025, 1, 31, 10, 15, 0, 0, time.UTC)},
+		{"0 9-17 * * mon-fri", time.Date(2025, 1, 31, 11, 0, 0, 0, time.UTC)},
+		{"30 6 * * sat", time.Date(2025, 2, 1, 6, 30, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 1 * 0", time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)}, // dom or dow
+		{"0 0 * * 7", time.Date(2025, 2, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		s, err := ParseCron(c.spec)
+		if err != nil {
+			t.Fatalf("ParseCron(%q): %v", c.spec, err)
+		}
+		if got := s.Next(base); !got.Equal(c.want) {
+			t.Errorf("ParseCron(%q).Next = %v; want %v", c.spec, got, c.want)
+		}
+	}
+
+	for _, bad := range []string{"", "* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "* * * foo *"} {
+		if _, err := ParseCron(bad); err == nil {
+			t.Errorf("ParseCron(%q) succeeded; want error", bad)
+		}
+	}
+}
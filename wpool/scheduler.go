@@ -0,0 +1,258 @@
+package workerpool
+
+import (
+	"container/heap"
+	"context"
+	"time"
+)
+
+// SchedulingMode selects how queued jobs of different priorities are ordered.
+type SchedulingMode int
+
+const (
+	// StrictPriority always hands out the highest priority job first.
+	// Jobs of equal priority run in submission order.
+	StrictPriority SchedulingMode = iota
+	// WeightedFair shares dispatches across priority levels in proportion to
+	// their weights, so low priority levels keep making progress.
+	WeightedFair
+)
+
+// task is the internal envelope of a queued job.
+type task[T any] struct {
+	job       Job[T]
+	id        uint64
+	handle    *Handle[T] // nil for recurring jobs
+	seq       uint64
+	enqueued  time.Time
+	rank      int64              // ordering key under StrictPriority
+	index     int                // position in taskHeap, maintained by heap operations
+	slot      bool               // holds one of the pool's queue slots
+	tslots    chan struct{}      // tenant queue slots it holds one of, if the tenant has a limit
+	reclaimed bool               // removed from the queue by ShutdownNow; guarded by the pool mutex
+	queued    bool               // in the scheduler; guarded by the pool mutex
+	attempts  int                // attempts made by the current run; written by its worker under the pool mutex
+	started   time.Time          // start of the current run; zero when not running; guarded by the pool mutex
+	cancel    context.CancelFunc // cancels the Job.Ctx of a tracked job
+	tries     []Attempt          // failed attempts of a batched job
+	batch     []*task[T]         // set on the envelope of a dispatched batch
+
+	// journal
+	qid       uint64
+	persisted bool
+
+	// delayed and recurring jobs
+	due        time.Time
+	sched      Schedule
+	waiting    bool        // in the pool's delayed heap; guarded by the pool mutex
+	stopCancel func() bool // unregisters the Job.Ctx cancellation hook
+}
+
+// scheduler orders queued tasks. peek must be side-effect free so the
+// dispatcher can re-evaluate when new work arrives; take commits the decision.
+// remove drops a task without counting it as dispatched, and each visits
+// every queued task in no particular order. Callers hold the pool mutex.
+type scheduler[T any] interface {
+	push(t *task[T])
+	peek() *task[T]
+	take(t *task[T])
+	remove(t *task[T])
+	each(fn func(t *task[T]))
+	len() int
+}
+
+func newScheduler[T any](cfg Config[T]) scheduler[T] {
+	if cfg.Scheduling == WeightedFair {
+		return newFairScheduler[T](cfg.Weights)
+	}
+	return newStrictScheduler[T](cfg.Aging)
+}
+
+// taskHeap is a heap.Interface over tasks that keeps task.index up to date.
+type taskHeap[T any] struct {
+	items []*task[T]
+	less  func(a, b *task[T]) bool
+}
+
+func (h *taskHeap[T]) Len() int           { return len(h.items) }
+func (h *taskHeap[T]) Less(i, j int) bool { return h.less(h.items[i], h.items[j]) }
+func (h *taskHeap[T]) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+	h.items[i].index = i
+	h.items[j].index = j
+}
+func (h *taskHeap[T]) Push(x any) {
+	t := x.(*task[T])
+	t.index = len(h.items)
+	h.items = append(h.items, t)
+}
+func (h *taskHeap[T]) Pop() any {
+	n := len(h.items) - 1
+	t := h.items[n]
+	h.items[n] = nil
+	h.items = h.items[:n]
+	t.index = -1
+	return t
+}
+
+func bySeq[T any](a, b *task[T]) bool { return a.seq < b.seq }
+
+func byDue[T any](a, b *task[T]) bool {
+	if !a.due.Equal(b.due) {
+		return a.due.Before(b.due)
+	}
+	return a.seq < b.seq
+}
+
+// strictScheduler is a single heap ordered by priority, then submission order.
+//
+// With aging enabled a job is ranked as if it had been submitted
+// Priority*aging earlier. Since every queued job ages at the same rate, the
+// rank is fixed at push time and the heap invariant holds without re-sorting,
+// while a long-waiting low priority job eventually overtakes fresh urgent ones.
+type strictScheduler[T any] struct {
+	h     taskHeap[T]
+	aging time.Duration
+	epoch time.Time
+}
+
+func newStrictScheduler[T any](aging time.Duration) *strictScheduler[T] {
+	s := &strictScheduler[T]{aging: aging, epoch: time.Now()}
+	s.h.less = func(a, b *task[T]) bool {
+		if s.aging > 0 {
+			if a.rank != b.rank {
+				return a.rank < b.rank
+			}
+		} else if a.job.Priority != b.job.Priority {
+			return a.job.Priority > b.job.Priority
+		}
+		return a.seq < b.seq
+	}
+	return s
+}
+
+func (s *strictScheduler[T]) push(t *task[T]) {
+	t.queued = true
+	if s.aging > 0 {
+		t.rank = int64(t.enqueued.Sub(s.epoch)) - int64(t.job.Priority)*int64(s.aging)
+	}
+	heap.Push(&s.h, t)
+}
+
+func (s *strictScheduler[T]) peek() *task[T] {
+	if s.h.Len() == 0 {
+		return nil
+	}
+	return s.h.items[0]
+}
+
+func (s *strictScheduler[T]) take(t *task[T]) { s.remove(t) }
+func (s *strictScheduler[T]) len() int        { return s.h.Len() }
+
+func (s *strictScheduler[T]) remove(t *task[T]) {
+	heap.Remove(&s.h, t.index)
+	t.queued = false
+}
+
+func (s *strictScheduler[T]) each(fn func(t *task[T])) {
+	for _, t := range s.h.items {
+		fn(t)
+	}
+}
+
+// fairLevel is the FIFO queue and smooth weighted round-robin credit of one
+// priority level.
+type fairLevel[T any] struct {
+	q       taskHeap[T]
+	weight  int
+	current int
+}
+
+// fairScheduler implements smooth weighted round-robin across priority levels.
+// Every level with queued work is guaranteed a share of dispatches, which
+// also makes it starvation-free.
+type fairScheduler[T any] struct {
+	levels  map[int]*fairLevel[T]
+	weights map[int]int
+	n       int
+}
+
+func newFairScheduler[T any](weights map[int]int) *fairScheduler[T] {
+	return &fairScheduler[T]{levels: make(map[int]*fairLevel[T]), weights: weights}
+}
+
+func (s *fairScheduler[T]) weight(priority int) int {
+	if w, ok := s.weights[priority]; ok && w > 0 {
+		return w
+	}
+	if priority > 0 {
+		return priority + 1
+	}
+	return 1
+}
+
+func (s *fairScheduler[T]) push(t *task[T]) {
+	lvl, ok := s.levels[t.job.Priority]
+	if !ok {
+		lvl = &fairLevel[T]{weight: s.weight(t.job.Priority)}
+		lvl.q.less = bySeq[T]
+		s.levels[t.job.Priority] = lvl
+	}
+	heap.Push(&lvl.q, t)
+	t.queued = true
+	s.n++
+}
+
+// pick returns the priority of the level that would be served next.
+func (s *fairScheduler[T]) pick() (int, bool) {
+	best, bestScore, found := 0, 0, false
+	for prio, lvl := range s.levels {
+		score := lvl.current + lvl.weight
+		if !found || score > bestScore || (score == bestScore && prio > best) {
+			best, bestScore, found = prio, score, true
+		}
+	}
+	return best, found
+}
+
+func (s *fairScheduler[T]) peek() *task[T] {
+	prio, ok := s.pick()
+	if !ok {
+		return nil
+	}
+	return s.levels[prio].q.items[0]
+}
+
+func (s *fairScheduler[T]) take(t *task[T]) {
+	lvl := s.levels[t.job.Priority]
+	if prio, ok := s.pick(); ok && prio == t.job.Priority && lvl.q.items[0] == t {
+		total := 0
+		for _, l := range s.levels {
+			l.current += l.weight
+			total += l.weight
+		}
+		lvl.current -= total
+	}
+	s.remove(t)
+}
+
+func (s *fairScheduler[T]) remove(t *task[T]) {
+	lvl := s.levels[t.job.Priority]
+	heap.Remove(&lvl.q, t.index)
+	t.queued = false
+	s.n--
+	if lvl.q.Len() == 0 {
+		// drop idle levels so stale credit does not carry over
+		delete(s.levels, t.job.Priority)
+	}
+}
+
+func (s *fairScheduler[T]) each(fn func(t *task[T])) {
+	for _, lvl := range s.levels {
+		for _, t := range lvl.q.items {
+			fn(t)
+		}
+	}
+}
+
+func (s *fairScheduler[T]) len() int { return s.n }
@@ -0,0 +1,132 @@
+package workerpool
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func drain[T any](s scheduler[T]) []int {
+	var order []int
+	for t := s.peek(); t != nil; t = s.peek() {
+		s.take(t)
+		order = append(order, t.job.Priority)
+	}
+	return order
+}
+
+func pushN[T any](s scheduler[T], seq *uint64, prio, n int, at time.Time) {
+	for i := 0; i < n; i++ {
+		*seq++
+		s.push(&task[T]{job: Job[T]{Priority: prio}, seq: *seq, enqueued: at})
+	}
+}
+
+func TestStrictSchedulerOrder(t *testing.T) {
+	s := newStrictScheduler[int](0)
+	var seq uint64
+	now := time.Now()
+	pushN[int](s, &seq, 0, 2, now)
+	pushN[int](s, &seq, 5, 1, now)
+	pushN[int](s, &seq, 2, 1, now)
+
+	got := drain[int](s)
+	want := []int{5, 2, 0, 0}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("order = %v; want %v", got, want)
+		}
+	}
+}
+
+func TestStrictSchedulerAging(t *testing.T) {
+	s := newStrictScheduler[int](10 * time.Millisecond)
+	var seq uint64
+	now := time.Now()
+	// a bulk job that has waited 100ms outranks an urgent job (+5 levels) submitted now
+	pushN[int](s, &seq, 0, 1, now.Add(-100*time.Millisecond))
+	pushN[int](s, &seq, 5, 1, now)
+
+	if got := drain[int](s); got[0] != 0 {
+		t.Fatalf("order = %v; want aged bulk job first", got)
+	}
+}
+
+func TestFairSchedulerShares(t *testing.T) {
+	s := newFairScheduler[int](map[int]int{1: 3, 0: 1})
+	var seq uint64
+	now := time.Now()
+	pushN[int](s, &seq, 1, 6, now)
+	pushN[int](s, &seq, 0, 6, now)
+
+	got := drain[int](s)
+	if len(got) != 12 || s.len() != 0 {
+		t.Fatalf("drained %d jobs, %d left; want 12, 0", len(got), s.len())
+	}
+	// within the first 8 dispatches the 3:1 ratio must hold exactly
+	low := 0
+	for _, p := range got[:8] {
+		if p == 0 {
+			low++
+		}
+	}
+	if low != 2 {
+		t.Fatalf("low priority dispatches in first 8 = %d; want 2 (order %v)", low, got)
+	}
+}
+
+func TestPoolRunsHighPriorityFirst(t *testing.T) {
+	p := NewPool[int](1, fastRetry)
+	defer p.Stop()
+
+	gate := make(chan struct{})
+	started := make(chan struct{})
+	_ = p.Submit(Job[int]{Fn: func(int) error {
+		close(started)
+		<-gate
+		return nil
+	}})
+	<-started
+
+	var mu sync.Mutex
+	var order []int
+	var wg sync.WaitGroup
+	record := func(n int) error {
+		mu.Lock()
+		order = append(order, n)
+		mu.Unlock()
+		wg.Done()
+		return nil
+	}
+	wg.Add(2)
+	_ = p.Submit(Job[int]{Payload: 0, Fn: record})
+	_ = p.Submit(Job[int]{Payload: 9, Priority: 9, Fn: record})
+	if got := p.QueueLength(); got != 2 {
+		t.Fatalf("queue length = %d; want 2", got)
+	}
+	close(gate)
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if order[0] != 9 || order[1] != 0 {
+		t.Fatalf("order = %v; want [9 0]", order)
+	}
+}
+
+func TestPoolWeightedFair(t *testing.T) {
+	p := NewPool[int](1, fastRetry, Config[int]{Scheduling: WeightedFair})
+	defer p.Stop()
+
+	done := make(chan struct{})
+	_ = p.Submit(Job[int]{Ctx: context.Background(), Priority: 3, Fn: func(int) error {
+		close(done)
+		return nil
+	}})
+	select {
+	case <-done:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("job did not run under WeightedFair")
+	}
+}
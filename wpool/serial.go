@@ -0,0 +1,100 @@
+package workerpool
+
+// Jobs that share a SerialKey run one at a time. While a key is busy, the
+// dispatcher parks further jobs of that key here instead of handing them to a
+// worker, so jobs of other keys keep flowing. Parked jobs keep their queue
+// slot and their original ordering, and return to the queue one by one as
+// the running job of their key finishes (after all its retries).
+
+// serialKey is the state of one busy key.
+type serialKey[T any] struct {
+	owner   *task[T]   // the job allowed to run: running, or requeued next
+	waiting []*task[T] // parked jobs in dispatch order
+}
+
+// serialBusy reports whether t must wait for another job of its key.
+// Callers hold p.mu.
+func (p *Pool[T]) serialBusy(t *task[T]) bool {
+	if t.job.SerialKey == "" || t.batched() {
+		return false
+	}
+	k, busy := p.serial[t.job.SerialKey]
+	return busy && k.owner != t
+}
+
+// park moves t from the queue to the wait list of its key. Parking is not a
+// dispatch, so it does not spend the scheduling credit of t's tenant or
+// priority. Callers hold p.mu.
+func (p *Pool[T]) park(t *task[T]) {
+	p.queue.remove(t)
+	k := p.serial[t.job.SerialKey]
+	k.waiting = append(k.waiting, t)
+	p.parked++
+}
+
+// holdKey marks the key of a dispatched job busy. Callers hold p.mu.
+func (p *Pool[T]) holdKey(t *task[T]) {
+	if t.job.SerialKey == "" || t.batched() {
+		return
+	}
+	if _, ok := p.serial[t.job.SerialKey]; !ok {
+		p.serial[t.job.SerialKey] = &serialKey[T]{owner: t}
+	}
+}
+
+// releaseSerial frees the key of a finished job and requeues the next job
+// waiting for it, if any.
+func (p *Pool[T]) releaseSerial(t *task[T]) {
+	if t.job.SerialKey == "" {
+		return
+	}
+	p.mu.Lock()
+	k, ok := p.serial[t.job.SerialKey]
+	if !ok || k.owner != t {
+		p.mu.Unlock()
+		return
+	}
+	if len(k.waiting) == 0 {
+		delete(p.serial, t.job.SerialKey)
+		p.mu.Unlock()
+		return
+	}
+	k.owner = k.waiting[0]
+	k.waiting[0] = nil
+	k.waiting = k.waiting[1:]
+	p.parked--
+	p.queue.push(k.owner) // keeps its seq and enqueue time, so it ranks as before
+	p.mu.Unlock()
+	p.wake()
+}
+
+// dropParked removes t from the wait list of its key and reports whether it
+// was parked there. Callers hold p.mu.
+func (p *Pool[T]) dropParked(t *task[T]) bool {
+	if t.job.SerialKey == "" {
+		return false
+	}
+	k, ok := p.serial[t.job.SerialKey]
+	if !ok {
+		return false
+	}
+	for i, w := range k.waiting {
+		if w == t {
+			k.waiting = append(k.waiting[:i], k.waiting[i+1:]...)
+			p.parked--
+			return true
+		}
+	}
+	return false
+}
+
+// unpark removes every parked job, for ShutdownNow. Callers hold p.mu.
+func (p *Pool[T]) unpark() []*task[T] {
+	var out []*task[T]
+	for _, k := range p.serial {
+		out = append(out, k.waiting...)
+		k.waiting = nil
+	}
+	p.parked = 0
+	return out
+}
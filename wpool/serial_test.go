@@ -0,0 +1,167 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSerialKeyRunsInOrder(t *testing.T) {
+	p := NewPool[int](4, fastRetry)
+	defer p.Stop()
+
+	var mu sync.Mutex
+	running := map[string]int{}
+	order := map[string][]int{}
+	parallel := false
+	fn := func(key string) JobFunc[int] {
+		return func(n int) error {
+			mu.Lock()
+			running[key]++
+			if running[key] > 1 {
+				mu.Unlock()
+				t.Errorf("key %s ran concurrently", key)
+				return nil
+			}
+			if running["a"] > 0 && running["b"] > 0 {
+				parallel = true
+			}
+			order[key] = append(order[key], n)
+			mu.Unlock()
+			time.Sleep(5 * time.Millisecond)
+			mu.Lock()
+			running[key]--
+			mu.Unlock()
+			return nil
+		}
+	}
+
+	var hs []*Handle[int]
+	for i := 0; i < 4; i++ {
+		for _, key := range []string{"a", "b"} {
+			h, err := p.SubmitHandle(Job[int]{Payload: i, SerialKey: key, Fn: fn(key)})
+			if err != nil {
+				t.Fatalf("submit: %v", err)
+			}
+			hs = append(hs, h)
+		}
+	}
+	for _, h := range hs {
+		_, _ = h.Wait(context.Background())
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, key := range []string{"a", "b"} {
+		for i, n := range order[key] {
+			if n != i {
+				t.Fatalf("key %s order = %v; want [0 1 2 3]", key, order[key])
+			}
+		}
+	}
+	if !parallel {
+		t.Fatal("different keys never ran in parallel")
+	}
+}
+
+func TestSerialKeyHeldDuringRetries(t *testing.T) {
+	p := NewPool[int](2, fastRetry)
+	defer p.Stop()
+
+	var mu sync.Mutex
+	var seq []string
+	first, _ := p.SubmitHandle(Job[int]{SerialKey: "k", Fn: func(int) error {
+		mu.Lock()
+		seq = append(seq, "first")
+		n := len(seq)
+		mu.Unlock()
+		if n < 2 {
+			return errors.New("retry me")
+		}
+		return nil
+	}})
+	second, _ := p.SubmitHandle(Job[int]{SerialKey: "k", Fn: func(int) error {
+		mu.Lock()
+		seq = append(seq, "second")
+		mu.Unlock()
+		return nil
+	}})
+	_, _ = first.Wait(context.Background())
+	_, _ = second.Wait(context.Background())
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(seq) != 3 || seq[0] != "first" || seq[1] != "first" || seq[2] != "second" {
+		t.Fatalf("sequence = %v; want [first first second]", seq)
+	}
+}
+
+func TestShutdownDrainsParkedJobs(t *testing.T) {
+	p := NewPool[int](2, fastRetry)
+	var mu sync.Mutex
+	ran := 0
+	for i := 0; i < 3; i++ {
+		_ = p.Submit(Job[int]{SerialKey: "k", Fn: func(int) error {
+			time.Sleep(2 * time.Millisecond)
+			mu.Lock()
+			ran++
+			mu.Unlock()
+			return nil
+		}})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := p.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if ran != 3 {
+		t.Fatalf("ran = %d; want 3", ran)
+	}
+}
+
+func TestParkedJobsKeepTenantShare(t *testing.T) {
+	p := NewPool[string](2, fastRetry, Config[string]{QueueCapacity: 100})
+	defer p.Stop()
+
+	gate := make(chan struct{})
+	started := make(chan struct{})
+	_ = p.Submit(Job[string]{Tenant: "a", SerialKey: "k", Fn: func(string) error { close(started); <-gate; return nil }})
+	<-started
+	defer close(gate)
+
+	var mu sync.Mutex
+	var order []string
+	done := make(chan struct{}, 12)
+	record := func(s string) error {
+		mu.Lock()
+		order = append(order, s)
+		mu.Unlock()
+		done <- struct{}{}
+		return nil
+	}
+	p.Pause()
+	// a's head is blocked on its key; parking those jobs must not count as
+	// a's turns
+	for i := 0; i < 6; i++ {
+		_ = p.Submit(Job[string]{Payload: "keyed", Tenant: "a", SerialKey: "k", Fn: record})
+	}
+	for i := 0; i < 3; i++ {
+		_ = p.Submit(Job[string]{Payload: "a", Tenant: "a", Fn: record})
+		_ = p.Submit(Job[string]{Payload: "b", Tenant: "b", Fn: record})
+	}
+	p.Resume()
+	for i := 0; i < 6; i++ {
+		<-done
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"a", "b", "a", "b", "a", "b"}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("order = %v; want %v", order, want)
+		}
+	}
+}
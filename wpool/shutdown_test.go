@@ -0,0 +1,153 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestShutdownDrainsQueue(t *testing.T) {
+	p := NewPool[int](1, fastRetry)
+	var ran atomic.Int32
+	for i := 0; i < 2; i++ {
+		_ = p.Submit(Job[int]{Fn: func(int) error {
+			time.Sleep(5 * time.Millisecond)
+			ran.Add(1)
+			return nil
+		}})
+	}
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if got := ran.Load(); got != 2 {
+		t.Fatalf("ran %d jobs; want 2", got)
+	}
+	if err := p.Submit(Job[int]{Fn: func(int) error { return nil }}); !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("Submit after Shutdown err = %v; want ErrPoolClosed", err)
+	}
+}
+
+func TestShutdownNowReturnsUnprocessed(t *testing.T) {
+	p := NewPool[int](1, fastRetry)
+
+	started := make(chan struct{})
+	running, _ := p.SubmitHandle(Job[int]{FnCtx: func(ctx context.Context, _ int) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+	<-started
+	queued, _ := p.SubmitHandle(Job[int]{Payload: 1, Fn: func(int) error { return nil }})
+	_ = p.Submit(Job[int]{Payload: 2, Fn: func(int) error { return nil }})
+	_ = p.SubmitAfter(time.Hour, Job[int]{Payload: 3, Fn: func(int) error { return nil }})
+
+	got := p.ShutdownNow()
+	if len(got) != 3 || got[0] != 1 || got[1] != 2 || got[2] != 3 {
+		t.Fatalf("ShutdownNow = %v; want [1 2 3]", got)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := p.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown after ShutdownNow: %v", err)
+	}
+	if res, _ := running.Wait(ctx); res.Status != StatusCanceled {
+		t.Fatalf("running job status = %v; want canceled", res.Status)
+	}
+	if res, _ := queued.Wait(ctx); res.Status != StatusCanceled || !errors.Is(res.Err, ErrPoolClosed) {
+		t.Fatalf("queued job result = %+v; want canceled with ErrPoolClosed", res)
+	}
+}
+
+// TestShutdownStress races submitters against Shutdown or ShutdownNow and
+// checks that every accepted job is accounted for exactly once.
+func TestShutdownStress(t *testing.T) {
+	for _, now := range []bool{false, true} {
+		for round := 0; round < 20; round++ {
+			p := NewPool[int](4, fastRetry, Config[int]{MinWorkers: 1})
+			var (
+				mu       sync.Mutex
+				accepted []*Handle[int]
+				ran      sync.Map
+				next     atomic.Int64
+				wg       sync.WaitGroup
+			)
+			fn := func(n int) error {
+				if _, dup := ran.LoadOrStore(n, true); dup {
+					t.Errorf("job %d ran twice", n)
+				}
+				return nil
+			}
+			for g := 0; g < 8; g++ {
+				wg.Add(1)
+				go func(g int) {
+					defer wg.Done()
+					for i := 0; i < 50; i++ {
+						n := int(next.Add(1))
+						job := Job[int]{Payload: n, Fn: fn, Priority: n % 3}
+						if g%2 == 0 {
+							if !p.TrySubmit(job) {
+								continue
+							}
+							ran.Store(-n, true) // accepted, no handle
+							continue
+						}
+						h, err := p.SubmitHandle(job)
+						if err != nil {
+							if !errors.Is(err, ErrPoolClosed) {
+								t.Errorf("SubmitHandle: %v", err)
+							}
+							return
+						}
+						mu.Lock()
+						accepted = append(accepted, h)
+						mu.Unlock()
+					}
+				}(g)
+			}
+
+			time.Sleep(time.Millisecond)
+			var returned []int
+			if now {
+				returned = p.ShutdownNow()
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := p.Shutdown(ctx); err != nil {
+				t.Fatalf("Shutdown: %v", err)
+			}
+			cancel()
+			wg.Wait()
+
+			for _, n := range returned {
+				if _, ok := ran.Load(n); ok {
+					t.Fatalf("job %d both ran and was returned", n)
+				}
+			}
+			for _, h := range accepted {
+				res, ok := h.Result()
+				if !ok {
+					t.Fatalf("job %d never completed", h.ID())
+				}
+				_, didRun := ran.Load(res.Payload)
+				switch {
+				case res.Status == StatusSucceeded && !didRun:
+					t.Fatalf("job %d succeeded without running", res.Payload)
+				case res.Status == StatusCanceled && (!now || didRun):
+					t.Fatalf("job %d canceled (now=%v, ran=%v)", res.Payload, now, didRun)
+				}
+			}
+			if !now {
+				ran.Range(func(k, _ any) bool {
+					if n := k.(int); n < 0 {
+						if _, ok := ran.Load(-n); !ok {
+							t.Errorf("accepted job %d was dropped by Shutdown", -n)
+						}
+					}
+					return true
+				})
+			}
+		}
+	}
+}
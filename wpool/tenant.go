@@ -0,0 +1,205 @@
+package workerpool
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// tenantQueue is the queue and smooth weighted round-robin credit of one
+// tenant.
+type tenantQueue[T any] struct {
+	q       scheduler[T]
+	weight  int
+	current int
+}
+
+// tenantScheduler shares dispatches between tenants by smooth weighted
+// round-robin, the same way WeightedFair shares them between priority
+// levels. Each tenant's jobs are ordered by its own scheduler of the
+// configured mode, so priorities apply within a tenant. With every job in
+// the default tenant it behaves exactly like that scheduler.
+type tenantScheduler[T any] struct {
+	tenants map[string]*tenantQueue[T]
+	weights map[string]int
+	newQ    func() scheduler[T]
+	n       int
+}
+
+func newTenantScheduler[T any](cfg Config[T]) *tenantScheduler[T] {
+	return &tenantScheduler[T]{
+		tenants: make(map[string]*tenantQueue[T]),
+		weights: cfg.TenantWeights,
+		newQ:    func() scheduler[T] { return newScheduler(cfg) },
+	}
+}
+
+func (s *tenantScheduler[T]) weight(tenant string) int {
+	if w, ok := s.weights[tenant]; ok && w > 0 {
+		return w
+	}
+	return 1
+}
+
+func (s *tenantScheduler[T]) push(t *task[T]) {
+	tq, ok := s.tenants[t.job.Tenant]
+	if !ok {
+		tq = &tenantQueue[T]{q: s.newQ(), weight: s.weight(t.job.Tenant)}
+		s.tenants[t.job.Tenant] = tq
+	}
+	tq.q.push(t)
+	s.n++
+}
+
+// pick returns the tenant that would be served next.
+func (s *tenantScheduler[T]) pick() (string, bool) {
+	best, bestScore, found := "", 0, false
+	for tenant, tq := range s.tenants {
+		score := tq.current + tq.weight
+		if !found || score > bestScore || (score == bestScore && tenant < best) {
+			best, bestScore, found = tenant, score, true
+		}
+	}
+	return best, found
+}
+
+func (s *tenantScheduler[T]) peek() *task[T] {
+	tenant, ok := s.pick()
+	if !ok {
+		return nil
+	}
+	return s.tenants[tenant].q.peek()
+}
+
+func (s *tenantScheduler[T]) take(t *task[T]) {
+	tq := s.tenants[t.job.Tenant]
+	if tenant, ok := s.pick(); ok && tenant == t.job.Tenant && tq.q.peek() == t {
+		total := 0
+		for _, other := range s.tenants {
+			other.current += other.weight
+			total += other.weight
+		}
+		tq.current -= total
+	}
+	tq.q.take(t)
+	s.drained(t.job.Tenant, tq)
+}
+
+func (s *tenantScheduler[T]) remove(t *task[T]) {
+	tq := s.tenants[t.job.Tenant]
+	tq.q.remove(t)
+	s.drained(t.job.Tenant, tq)
+}
+
+// drained accounts for a task leaving tq and drops tq once it is empty, so
+// stale credit does not carry over.
+func (s *tenantScheduler[T]) drained(tenant string, tq *tenantQueue[T]) {
+	s.n--
+	if tq.q.len() == 0 {
+		delete(s.tenants, tenant)
+	}
+}
+
+func (s *tenantScheduler[T]) each(fn func(t *task[T])) {
+	for _, tq := range s.tenants {
+		tq.q.each(fn)
+	}
+}
+
+func (s *tenantScheduler[T]) len() int { return s.n }
+
+// queued returns the number of queued jobs of tenant.
+func (s *tenantScheduler[T]) queued(tenant string) int {
+	if tq, ok := s.tenants[tenant]; ok {
+		return tq.q.len()
+	}
+	return 0
+}
+
+// TenantStats is a snapshot of one tenant's counters.
+type TenantStats struct {
+	Queued    int // jobs in the queue right now
+	Submitted uint64
+	Started   uint64
+	Succeeded uint64
+	Failed    uint64
+	Canceled  uint64
+	Dropped   uint64
+}
+
+type tenantCounters struct {
+	submitted, started, succeeded, failed, canceled, dropped atomic.Uint64
+}
+
+// tenantStats holds the counters of every named tenant seen so far.
+type tenantStats struct {
+	mu sync.Mutex
+	m  map[string]*tenantCounters
+}
+
+// tenantMetrics returns the counters of tenant, or nil for the default
+// tenant, which only shows up in the pool-wide Stats.
+func (p *Pool[T]) tenantMetrics(tenant string) *tenantCounters {
+	if tenant == "" {
+		return nil
+	}
+	ts := &p.tenantStats
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	c, ok := ts.m[tenant]
+	if !ok {
+		if ts.m == nil {
+			ts.m = make(map[string]*tenantCounters)
+		}
+		c = new(tenantCounters)
+		ts.m[tenant] = c
+	}
+	return c
+}
+
+// tenantSnapshot returns the counters of every named tenant.
+func (p *Pool[T]) tenantSnapshot() map[string]TenantStats {
+	ts := &p.tenantStats
+	ts.mu.Lock()
+	out := make(map[string]TenantStats, len(ts.m))
+	for tenant, c := range ts.m {
+		out[tenant] = TenantStats{
+			Submitted: c.submitted.Load(),
+			Started:   c.started.Load(),
+			Succeeded: c.succeeded.Load(),
+			Failed:    c.failed.Load(),
+			Canceled:  c.canceled.Load(),
+			Dropped:   c.dropped.Load(),
+		}
+	}
+	ts.mu.Unlock()
+	if len(out) == 0 {
+		return nil
+	}
+	p.mu.Lock()
+	for tenant, s := range out {
+		s.Queued = p.queue.queued(tenant)
+		out[tenant] = s
+	}
+	p.mu.Unlock()
+	return out
+}
+
+// tenantSlots returns the queue slots of tenant, or nil if it has no
+// queue limit.
+func (p *Pool[T]) tenantSlots(tenant string) chan struct{} {
+	limit := p.tenantLimit
+	if l, ok := p.tenantLimits[tenant]; ok {
+		limit = l
+	}
+	if limit <= 0 {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	sem, ok := p.tenantSem[tenant]
+	if !ok {
+		sem = make(chan struct{}, limit)
+		p.tenantSem[tenant] = sem
+	}
+	return sem
+}
@@ -0,0 +1,165 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTenantSchedulerWeights(t *testing.T) {
+	s := newTenantScheduler(Config[int]{TenantWeights: map[string]int{"a": 3}})
+	var seq uint64
+	push := func(tenant string, n int) {
+		for i := 0; i < n; i++ {
+			seq++
+			s.push(&task[int]{job: Job[int]{Tenant: tenant}, seq: seq})
+		}
+	}
+	push("a", 20)
+	push("b", 20)
+
+	counts := map[string]int{}
+	for i := 0; i < 8; i++ {
+		next := s.peek()
+		s.take(next)
+		counts[next.job.Tenant]++
+	}
+	if counts["a"] != 6 || counts["b"] != 2 {
+		t.Fatalf("first 8 dispatches = %v; want a:6 b:2", counts)
+	}
+	if s.len() != 32 || s.queued("a") != 14 {
+		t.Fatalf("len = %d, queued(a) = %d; want 32 and 14", s.len(), s.queued("a"))
+	}
+}
+
+func TestTenantsShareThePool(t *testing.T) {
+	p := NewPool[string](1, fastRetry, Config[string]{QueueCapacity: 100})
+	defer p.Stop()
+
+	gate := make(chan struct{})
+	started := make(chan struct{})
+	_ = p.Submit(Job[string]{Fn: func(string) error { close(started); <-gate; return nil }})
+	<-started
+
+	var mu sync.Mutex
+	var order []string
+	record := func(s string) error {
+		mu.Lock()
+		order = append(order, s)
+		mu.Unlock()
+		return nil
+	}
+	for i := 0; i < 50; i++ {
+		_ = p.Submit(Job[string]{Payload: "bulk", Tenant: "importer", Fn: record})
+	}
+	for i := 0; i < 2; i++ {
+		_ = p.Submit(Job[string]{Payload: "small", Tenant: "web", Fn: record})
+	}
+	close(gate)
+	p.Stop()
+
+	// the importer's backlog does not delay the web tenant past a few turns
+	last := -1
+	for i, s := range order {
+		if s == "small" {
+			last = i
+		}
+	}
+	if last < 0 || last > 4 {
+		t.Fatalf("web jobs finished at position %d of %d; want within the first 5", last, len(order))
+	}
+	stats := p.Stats().Tenants
+	if s := stats["importer"]; s.Submitted != 50 || s.Succeeded != 50 || s.Queued != 0 {
+		t.Fatalf("importer stats = %+v", s)
+	}
+	if s := stats["web"]; s.Submitted != 2 || s.Started != 2 || s.Succeeded != 2 {
+		t.Fatalf("web stats = %+v", s)
+	}
+	if _, ok := stats[""]; ok {
+		t.Fatal("default tenant has its own stats")
+	}
+}
+
+func TestTenantQueueLimit(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		overflow OverflowPolicy
+	}{{"reject", OverflowReject}, {"drop oldest", OverflowDropOldest}} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := NewPool[int](1, fastRetry, Config[int]{
+				QueueCapacity:     10,
+				Overflow:          tc.overflow,
+				TenantQueueLimit:  2,
+				TenantQueueLimits: map[string]int{"vip": 0},
+			})
+			defer p.Stop()
+
+			gate := make(chan struct{})
+			started := make(chan struct{})
+			_ = p.Submit(Job[int]{Tenant: "vip", Fn: func(int) error { close(started); <-gate; return nil }})
+			<-started
+			defer close(gate)
+
+			noop := func(int) error { return nil }
+			var handles []*Handle[int]
+			for i := 0; i < 2; i++ {
+				h, err := p.SubmitHandle(Job[int]{Tenant: "a", Fn: noop})
+				if err != nil {
+					t.Fatalf("submit %d: %v", i, err)
+				}
+				handles = append(handles, h)
+			}
+			_, err := p.SubmitHandle(Job[int]{Tenant: "a", Fn: noop})
+			switch tc.overflow {
+			case OverflowReject:
+				if !errors.Is(err, ErrQueueFull) {
+					t.Fatalf("third job of a: err = %v; want ErrQueueFull", err)
+				}
+			case OverflowDropOldest:
+				if err != nil {
+					t.Fatalf("third job of a: %v", err)
+				}
+				ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+				defer cancel()
+				if res, _ := handles[0].Wait(ctx); !errors.Is(res.Err, ErrJobDropped) {
+					t.Fatalf("oldest job of a = %+v; want dropped", res)
+				}
+				if s := p.Stats().Tenants["a"]; s.Dropped != 1 || s.Queued != 2 {
+					t.Fatalf("a stats = %+v", s)
+				}
+			}
+			for i := 0; i < 3; i++ {
+				if err := p.Submit(Job[int]{Tenant: "b", Fn: noop}); err != nil && i < 2 {
+					t.Fatalf("b is limited by a's jobs: %v", err)
+				}
+				if err := p.Submit(Job[int]{Tenant: "vip", Fn: noop}); err != nil {
+					t.Fatalf("vip has no limit: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestFileQueueTenant(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.log")
+	q, err := OpenFileQueue[int](path, nil)
+	if err != nil {
+		t.Fatalf("OpenFileQueue: %v", err)
+	}
+	_, _ = q.Put(Record[int]{Payload: 1})
+	_, _ = q.Put(Record[int]{Payload: 2, Tenant: "acme", Priority: 3})
+	_ = q.Close()
+
+	q, err = OpenFileQueue[int](path, nil)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer q.Close()
+	recs, _ := q.Pending()
+	if len(recs) != 2 || recs[0].Tenant != "" || recs[1].Tenant != "acme" || recs[1].Payload != 2 || recs[1].Priority != 3 {
+		t.Fatalf("records = %+v", recs)
+	}
+}
@@ -2,46 +2,208 @@ package workerpool
 
 import (
 	"context"
-	"fmt"
-	boff "github.com/azargarov/go-utils/backoff"
+	"errors"
 	lg "github.com/azargarov/go-utils/zlog"
+	"runtime/debug"
 	"sync"
 	"sync/atomic"
 	"time"
 )
 
+// ErrPoolClosed is returned when submitting to a pool that is shutting down.
+var ErrPoolClosed = errors.New("workerpool: pool closed")
+
 const (
 	DefaultMaxWorkers   = 10
 	defaultAttempts     = 3
 	defaultInitialRetry = 200 * time.Millisecond
 	defauiltMaxRetry    = 5 * time.Second
+	defaultQueueRatio   = 2 // default queue capacity per worker
 )
 
 type RetryPolicy struct {
 	Attempts int
 	Initial  time.Duration
 	Max      time.Duration
+	Timeout  time.Duration // per-attempt deadline; 0 means none
+
+	// IsRetryable reports whether a failed attempt may be retried at all.
+	// Nil retries every error.
+	IsRetryable func(error) bool
+	// Backoff creates the delay sequence; nil uses backoff.New(Initial, Max).
+	Backoff BackoffFunc
+	// Rules override Attempts and the backoff for matching errors; the first
+	// matching rule wins.
+	Rules []RetryRule
 }
 
 type JobFunc[T any] func(T) error
 
+// JobFuncCtx is a context-aware job function. ctx is derived from Job.Ctx per
+// attempt; it is canceled when the attempt times out, when Job.Ctx is
+// canceled, or when Shutdown's grace period runs out.
+type JobFuncCtx[T any] func(ctx context.Context, payload T) error
+
 type Job[T any] struct {
 	Payload     T
 	Fn          JobFunc[T]
+	FnCtx       JobFuncCtx[T] // takes precedence over Fn
 	Ctx         context.Context
 	CleanupFunc func()
 	Retry       *RetryPolicy
+	Priority    int       // higher runs first; 0 is the default level
+	RunAt       time.Time // if in the future, the job is held until then
+	Key         string    // optional idempotency key; see Config.Dedup
+	SerialKey   string    // jobs sharing a SerialKey run one at a time, in order
+	Tenant      string    // jobs are shared fairly between tenants; see Config.TenantWeights
+
+	remote *remoteRun // set on jobs fed by a Remote
+}
+
+// Config holds optional pool settings. The zero value gives strict
+// priority scheduling without aging.
+type Config[T any] struct {
+	// Scheduling selects how queued jobs are ordered across priority levels.
+	Scheduling SchedulingMode
+	// Aging protects low priority jobs from starvation under StrictPriority:
+	// a queued job gains one priority level for every Aging it waits.
+	// Zero disables aging.
+	Aging time.Duration
+	// Weights maps a priority level to its share under WeightedFair.
+	// Levels without an entry get weight Priority+1 (at least 1).
+	Weights map[int]int
+	// Queue journals accepted jobs so they survive a restart. Pending records
+	// are replayed by NewPool. The pool does not close the queue. Defaults to
+	// a non-durable MemoryQueue.
+	Queue Queue[T]
+	// Handler runs jobs submitted without Fn, including replayed ones.
+	Handler JobFunc[T]
+	// HandlerCtx is the context-aware form of Handler and takes precedence.
+	HandlerCtx JobFuncCtx[T]
+	// DeadLetter receives jobs whose final attempt failed. Nil drops them
+	// after logging, as before.
+	DeadLetter DeadLetterSink[T]
+	// ShutdownGrace bounds how long Shutdown lets in-flight attempts run:
+	// once it elapses their contexts are canceled, no further retries are
+	// made and queued jobs are skipped (journaled jobs stay pending). Zero
+	// waits for jobs to finish on their own.
+	ShutdownGrace time.Duration
+	// Results, if set, receives the outcome of every job, including each run
+	// of a recurring job. Sends block the worker, so keep reading it or give
+	// it enough buffer.
+	Results chan<- Result[T]
+	// QueueCapacity bounds the number of queued jobs, not counting delayed
+	// ones. Default maxWorkers*2.
+	QueueCapacity int
+	// Overflow selects what a submission does when the queue is full.
+	// Default OverflowBlock.
+	Overflow OverflowPolicy
+	// OnPanic selects what happens to a job whose function panics.
+	// Default PanicFail.
+	OnPanic PanicPolicy
+
+	// TenantWeights maps a Job.Tenant to its share of dispatches; tenants
+	// take turns by weighted round-robin so one tenant's backlog cannot
+	// starve the others. Tenants without an entry, including the default
+	// "" tenant, get weight 1.
+	TenantWeights map[string]int
+	// TenantQueueLimit caps the queued jobs of each tenant. Overflow applies
+	// when a tenant is at its limit, and OverflowDropOldest then evicts
+	// from that tenant only. Zero leaves tenants bounded by QueueCapacity
+	// alone.
+	TenantQueueLimit int
+	// TenantQueueLimits overrides TenantQueueLimit per tenant; zero means
+	// no limit.
+	TenantQueueLimits map[string]int
+
+	// Batch enables batch mode for jobs submitted without Fn (see BatchConfig).
+	// It takes precedence over Handler.
+	Batch BatchConfig[T]
+	// Hooks observe the job lifecycle and carry the tracing integration.
+	Hooks Hooks[T]
+	// RateLimit caps job attempts (including retries) at this many per
+	// second across all workers. Zero means unlimited.
+	RateLimit float64
+	// RateBurst is the number of attempts that may start at once after an
+	// idle period. Default 1.
+	RateBurst int
+	// Dedup selects how a job whose Key matches a queued, running or
+	// recently succeeded job is handled. Jobs without a Key are never
+	// deduplicated.
+	Dedup DedupMode
+	// DedupTTL keeps the Key of a succeeded job for this long after it
+	// completes. Zero forgets keys as soon as their job finishes.
+	DedupTTL time.Duration
+	// Log controls the pool's logger, log levels, sampling and payload
+	// rendering.
+	Log LogConfig[T]
+
+	// MinWorkers enables autoscaling between MinWorkers and maxWorkers.
+	// Zero keeps a fixed pool of maxWorkers goroutines.
+	MinWorkers int
+	// IdleTimeout retires a worker above MinWorkers after it has been idle
+	// this long. Default 30s.
+	IdleTimeout time.Duration
+	// ScaleUpQueueLength starts another worker when no worker is idle and at
+	// least this many jobs are queued. Default 1.
+	ScaleUpQueueLength int
+	// ScaleUpWait starts another worker when no worker is idle and the next
+	// job has waited this long, even below ScaleUpQueueLength. Zero disables
+	// the wait trigger.
+	ScaleUpWait time.Duration
 }
 
 type Pool[T any] struct {
-	jobs           chan Job[T]
-	wg             sync.WaitGroup
-	maxWorkers     int
-	activeWorkers  atomic.Int32
-	stopOnce       sync.Once
-	closed         chan struct{} // signals no more submissions
-	defaultRetry   RetryPolicy
-	submitBufRatio int
+	mu            sync.Mutex
+	queue         *tenantScheduler[T]
+	delayed       taskHeap[T]   // jobs waiting for RunAt, ordered by due time
+	isClosed      bool          // guarded by mu; set together with closed
+	slots         chan struct{} // bounds the number of queued jobs
+	overflow      OverflowPolicy
+	paused        bool                // guarded by mu
+	jobs          map[uint64]*task[T] // queued, delayed and running jobs; guarded by mu
+	tenantLimit   int
+	tenantLimits  map[string]int
+	tenantSem     map[string]chan struct{} // per-tenant queue slots; guarded by mu
+	tenantStats   tenantStats
+	onPanic       PanicPolicy
+	notify        chan struct{} // wakes the dispatcher when work is queued
+	work          chan *task[T] // hands dispatched jobs to workers
+	handoff       chan bool     // follows each send on work: false if ShutdownNow reclaimed the job
+	seq           uint64
+	journal       Queue[T]
+	handler       JobFuncCtx[T]
+	deadLetters   DeadLetterSink[T]
+	runCtx        context.Context // parent of every attempt; canceled to abort
+	abort         context.CancelFunc
+	grace         time.Duration
+	results       chan<- Result[T]
+	hooks         Hooks[T]
+	logs          logSettings[T]
+	batchCfg      BatchConfig[T]
+	pending       []*task[T] // batch being collected; guarded by mu
+	pendingSince  time.Time
+	limiter       *rateLimiter
+	serial        map[string]*serialKey[T] // busy serial keys; guarded by mu
+	parked        int                      // jobs waiting in serial; guarded by mu
+	dedup         DedupMode
+	dedupTTL      time.Duration
+	keys          map[string]*keyEntry[T] // guarded by mu
+	seen          []seenKey               // completed keys by expiry; guarded by mu
+	metrics       *metrics
+	nextID        atomic.Uint64
+	wg            sync.WaitGroup
+	maxWorkers    atomic.Int32
+	minWorkers    atomic.Int32
+	workers       atomic.Int32                  // live worker goroutines
+	resized       atomic.Pointer[chan struct{}] // closed and replaced by Resize
+	idleTimeout   time.Duration
+	scaleUpQueue  int
+	scaleUpWait   time.Duration
+	activeWorkers atomic.Int32
+	stopOnce      sync.Once
+	closed        chan struct{} // signals no more submissions
+	defaultRetry  RetryPolicy
 }
 
 func GetDefaultRP() *RetryPolicy {
@@ -53,7 +215,7 @@ func GetDefaultRP() *RetryPolicy {
 	return &rp
 }
 
-func NewPool[T any](maxWorkers int, defaultRetry RetryPolicy) *Pool[T] {
+func NewPool[T any](maxWorkers int, defaultRetry RetryPolicy, config ...Config[T]) *Pool[T] {
 	if maxWorkers <= 0 {
 		maxWorkers = DefaultMaxWorkers
 	}
@@ -67,36 +229,69 @@ func NewPool[T any](maxWorkers int, defaultRetry RetryPolicy) *Pool[T] {
 		defaultRetry.Max = defauiltMaxRetry
 	}
 
+	var cfg Config[T]
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	if cfg.Queue == nil {
+		cfg.Queue = NewMemoryQueue[T]()
+	}
+
+	handler := cfg.HandlerCtx
+	if handler == nil && cfg.Handler != nil {
+		handler = adapt(cfg.Handler)
+	}
+	cfg.Batch.normalize()
+	if cfg.QueueCapacity <= 0 {
+		cfg.QueueCapacity = maxWorkers * defaultQueueRatio
+	}
+
 	p := &Pool[T]{
-		jobs:           make(chan Job[T], maxWorkers*2),
-		maxWorkers:     maxWorkers,
-		closed:         make(chan struct{}),
-		defaultRetry:   defaultRetry,
-		submitBufRatio: 2,
+		queue:        newTenantScheduler(cfg),
+		journal:      cfg.Queue,
+		handler:      handler,
+		deadLetters:  cfg.DeadLetter,
+		grace:        cfg.ShutdownGrace,
+		results:      cfg.Results,
+		hooks:        cfg.Hooks,
+		logs:         newLogSettings(cfg.Log),
+		batchCfg:     cfg.Batch,
+		limiter:      newRateLimiter(cfg.RateLimit, cfg.RateBurst),
+		serial:       make(map[string]*serialKey[T]),
+		dedup:        cfg.Dedup,
+		dedupTTL:     cfg.DedupTTL,
+		keys:         make(map[string]*keyEntry[T]),
+		jobs:         make(map[uint64]*task[T]),
+		tenantLimit:  cfg.TenantQueueLimit,
+		tenantLimits: cfg.TenantQueueLimits,
+		tenantSem:    make(map[string]chan struct{}),
+		metrics:      newMetrics(),
+		notify:       make(chan struct{}, 1),
+		work:         make(chan *task[T]),
+		handoff:      make(chan bool),
+		closed:       make(chan struct{}),
+		slots:        make(chan struct{}, cfg.QueueCapacity),
+		overflow:     cfg.Overflow,
+		onPanic:      cfg.OnPanic,
+		defaultRetry: defaultRetry,
 	}
-	for i := 0; i < p.maxWorkers; i++ {
-		p.wg.Add(1)
-		go p.worker()
+	p.initScaling(maxWorkers, cfg)
+	p.runCtx, p.abort = context.WithCancel(context.Background())
+	p.delayed.less = byDue[T]
+	p.replay()
+
+	p.wg.Add(1)
+	go p.dispatch()
+	for i := int32(0); i < p.minWorkers.Load(); i++ {
+		p.spawn()
 	}
 	return p
 }
 
-// None-blocking pull stop
+// Shutdown rejects new jobs, drains the queue and waits for workers until ctx is done.
 func (p *Pool[T]) Shutdown(ctx context.Context) error {
-	var already bool
-	p.stopOnce.Do(func() {
-		close(p.closed) // reject new jobs
-		close(p.jobs)   // drain
-	})
-	// detect if we already closed before
-	select {
-	case <-p.closed:
-		already = true
-	default:
-	}
-	if !already {
-		close(p.closed)
-	}
+	p.close()
 	done := make(chan struct{})
 	go func() {
 		defer close(done)
@@ -104,117 +299,534 @@ func (p *Pool[T]) Shutdown(ctx context.Context) error {
 	}()
 	select {
 	case <-done:
+		p.abort()
 		return nil
 	case <-ctx.Done():
 		return ctx.Err()
 	}
 }
 
-// blocking stop
+// ShutdownNow closes the pool, cancels the contexts of running jobs and
+// returns the payloads of queued and delayed one-shot jobs that never started,
+// in dispatch order. Their handles complete as StatusCanceled and journaled
+// ones stay pending for replay. It does not wait for workers to exit; call
+// Shutdown afterwards for that.
+func (p *Pool[T]) ShutdownNow() []T {
+	p.mu.Lock()
+	p.isClosed = true
+	var queued []*task[T]
+	for t := p.queue.peek(); t != nil; t = p.queue.peek() {
+		p.queue.take(t)
+		t.reclaimed = true
+		queued = append(queued, t)
+	}
+	queued = append(queued, p.pending...)
+	p.pending = nil
+	queued = append(queued, p.unpark()...)
+	delayed := p.dropDelayed()
+	p.mu.Unlock()
+	p.close()
+	p.abort()
+
+	var payloads []T
+	for _, t := range queued {
+		if t.slot {
+			p.release(t)
+		}
+		if t.sched == nil {
+			payloads = append(payloads, t.job.Payload)
+		}
+		p.discard(t, false)
+	}
+	for _, t := range delayed {
+		if t.sched == nil {
+			payloads = append(payloads, t.job.Payload)
+		}
+		p.discard(t, false)
+	}
+	return payloads
+}
+
+// close rejects new jobs and lets the dispatcher drain the queue.
+func (p *Pool[T]) close() {
+	p.stopOnce.Do(func() {
+		p.mu.Lock()
+		p.isClosed = true
+		p.paused = false // a paused pool still drains
+		p.mu.Unlock()
+		close(p.closed)
+		if p.grace > 0 {
+			time.AfterFunc(p.grace, p.abort)
+		}
+	})
+}
+
 func (p *Pool[T]) Stop() { _ = p.Shutdown(context.Background()) }
 
+// Submit queues a job. When the queue is full it applies Config.Overflow;
+// under OverflowBlock it waits until a slot frees up or Job.Ctx is done.
 func (p *Pool[T]) Submit(job Job[T]) error {
-	if job.Ctx == nil {
-		job.Ctx = context.Background()
+	_, err := p.SubmitHandle(job)
+	return err
+}
+
+// SubmitHandle queues a job like Submit and returns a Handle to track it.
+func (p *Pool[T]) SubmitHandle(job Job[T]) (*Handle[T], error) {
+	return p.submit(context.Background(), &job, true)
+}
+
+// Non-blocking submit.
+func (p *Pool[T]) TrySubmit(job Job[T]) bool {
+	_, err := p.submit(context.Background(), &job, false)
+	return err == nil
+}
+
+// submit journals job and queues it, or parks it until job.RunAt.
+// With block set a full queue under OverflowBlock waits until ctx is done.
+func (p *Pool[T]) submit(ctx context.Context, job *Job[T], block bool) (_ *Handle[T], err error) {
+	if err := p.prepare(job); err != nil {
+		return nil, err
 	}
 	select {
 	case <-p.closed:
-		return fmt.Errorf("workerpool: pool closed")
+		return nil, ErrPoolClosed
 	default:
 	}
-	select {
-	case p.jobs <- job:
-		lg.FromContext(job.Ctx).Info("Job submitted", lg.Any("job", job.Payload))
-		return nil
-	case <-p.closed:
-		return fmt.Errorf("workerpool: pool closed")
+
+	t := p.newTask(*job)
+	if job.Key != "" {
+		p.mu.Lock()
+		h, ok := p.claimKey(job.Key, t.handle)
+		p.mu.Unlock()
+		if !ok {
+			if p.dedup == DedupCoalesce {
+				return h, nil
+			}
+			return nil, ErrDuplicate
+		}
+		defer func() {
+			if err != nil {
+				p.releaseKey(job.Key, t.handle, StatusCanceled)
+			}
+		}()
+	}
+	if job.RunAt.After(time.Now()) {
+		t.due = job.RunAt
+		if err := p.persist(t); err != nil {
+			return nil, err
+		}
+		if !p.delay(t) {
+			p.ack(t)
+			return nil, ErrPoolClosed
+		}
+		p.submitted(t)
+		return t.handle, nil
+	}
+	job.RunAt = time.Time{}
+
+	ok, err := p.acquire(ctx, t, block)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		p.drop(t)
+		return t.handle, nil
+	}
+	t.slot = true
+	if err := p.persist(t); err != nil {
+		p.release(t)
+		return nil, err
 	}
+	if !p.enqueue(t) {
+		p.release(t)
+		p.ack(t)
+		return nil, ErrPoolClosed
+	}
+	p.submitted(t)
+	return t.handle, nil
 }
 
-// Non-blocking submit.
-func (p *Pool[T]) TrySubmit(job Job[T]) bool {
+// prepare fills in job defaults and resolves the function to run into FnCtx.
+func (p *Pool[T]) prepare(job *Job[T]) error {
 	if job.Ctx == nil {
 		job.Ctx = context.Background()
 	}
-	select {
-	case <-p.closed:
+	if job.FnCtx == nil && job.Fn != nil {
+		job.FnCtx = adapt(job.Fn)
+	}
+	if job.FnCtx == nil && p.batchCfg.Fn == nil {
+		job.FnCtx = p.handler
+	}
+	if job.FnCtx == nil && p.batchCfg.Fn == nil {
+		return errors.New("workerpool: job has no Fn and the pool has no Handler")
+	}
+	return nil
+}
+
+func adapt[T any](fn JobFunc[T]) JobFuncCtx[T] {
+	return func(_ context.Context, payload T) error { return fn(payload) }
+}
+
+// enqueue hands t to the scheduler. It reports false if the pool is closed.
+func (p *Pool[T]) enqueue(t *task[T]) bool {
+	p.mu.Lock()
+	if p.isClosed {
+		p.mu.Unlock()
 		return false
-	default:
 	}
+	p.track(t)
+	p.seq++
+	t.seq = p.seq
+	t.enqueued = time.Now()
+	p.queue.push(t)
+	p.mu.Unlock()
+	p.wake()
+	return true
+}
+
+// wake nudges the dispatcher to re-evaluate the queue.
+func (p *Pool[T]) wake() {
 	select {
-	case p.jobs <- job:
-		return true
+	case p.notify <- struct{}{}:
 	default:
+	}
+}
+
+// dispatch offers the scheduler's best task to the next idle worker. The
+// choice is re-evaluated whenever new work arrives before a worker is free,
+// so a late urgent job still overtakes queued bulk work. After Shutdown it
+// drains the queue and then releases the workers.
+//
+// Delayed jobs become eligible when they are due; jobs that are not yet due
+// when the pool shuts down are discarded.
+func (p *Pool[T]) dispatch() {
+	defer p.wg.Done()
+	defer close(p.work)
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	defer timer.Stop()
+	growTimer := time.NewTimer(time.Hour)
+	growTimer.Stop()
+	defer growTimer.Stop()
+	batchTimer := time.NewTimer(time.Hour)
+	batchTimer.Stop()
+	defer batchTimer.Stop()
+	var ready *task[T] // a collected batch waiting for a worker
+	for {
+		var dropped []*task[T]
+		now := time.Now()
+		p.mu.Lock()
+		wait, pending := p.promoteDue(now)
+		if p.isClosed && pending {
+			dropped, pending = p.dropDelayed(), false
+		}
+		var t *task[T]
+		for !p.paused {
+			if ready == nil && p.batchCfg.Fn != nil {
+				ready = p.collect(now)
+			}
+			if t = p.queue.peek(); t == nil || !p.serialBusy(t) {
+				break
+			}
+			p.park(t)
+		}
+		if ready != nil && !p.paused {
+			t = ready
+		}
+		queued := p.queue.len()
+		collecting := len(p.pending) > 0
+		flushIn := p.batchCfg.Wait - now.Sub(p.pendingSince)
+		closed := p.closed
+		if p.isClosed {
+			closed = nil // already observed; parked jobs still need a wake-up
+		}
+		done := t == nil && p.isClosed && !collecting && p.parked == 0
+		p.mu.Unlock()
+
+		for _, d := range dropped {
+			p.discard(d, false)
+		}
+		if done {
+			return
+		}
+		var due <-chan time.Time
+		if pending {
+			timer.Reset(wait)
+			due = timer.C
+		}
+		var flush <-chan time.Time
+		if collecting && ready == nil {
+			batchTimer.Reset(flushIn)
+			flush = batchTimer.C
+		}
+		if t == nil {
+			select {
+			case <-p.notify:
+			case <-closed:
+			case <-due:
+			case <-flush:
+			}
+			continue
+		}
+		select {
+		case p.work <- t:
+			if t == ready {
+				ready = nil
+			}
+			p.handoff <- p.dispatched(t)
+			continue
+		default:
+		}
+		// no idle worker: grow the pool if the backlog warrants it
+		var grow <-chan time.Time
+		if after, ok := p.growAfter(t, queued); ok {
+			if after <= 0 {
+				p.spawn()
+			} else {
+				growTimer.Reset(after)
+				grow = growTimer.C
+			}
+		}
+		select {
+		case p.work <- t:
+			if t == ready {
+				ready = nil
+			}
+			p.handoff <- p.dispatched(t)
+		case <-p.notify:
+		case <-due:
+		case <-grow:
+		case <-flush:
+		}
+	}
+}
+
+// dispatched removes a job that was just handed to a worker from the queue.
+// It reports false if ShutdownNow reclaimed the job first; the worker must
+// then drop it. The worker waits for this on p.handoff, so the queue is
+// updated before the job can run (and a recurring job be rearmed).
+func (p *Pool[T]) dispatched(t *task[T]) bool {
+	if t.batch != nil {
+		return true // batches are collected out of the queue already
+	}
+	p.mu.Lock()
+	if t.reclaimed {
+		p.mu.Unlock()
 		return false
 	}
+	p.queue.take(t)
+	p.holdKey(t)
+	p.mu.Unlock()
+	if t.slot {
+		p.release(t)
+		t.slot = false
+	}
+	return true
 }
 
+// worker runs dispatched jobs until the pool closes, the pool is resized
+// below the live worker count, or it idles out above the minimum size.
 func (p *Pool[T]) worker() {
 	defer p.wg.Done()
-	for job := range p.jobs {
-		p.activeWorkers.Add(1)
-		func() {
-			defer p.activeWorkers.Add(-1)
-			defer func() {
-				if r := recover(); r != nil {
-					lg.FromContext(job.Ctx).Error("job panicked", lg.Any("panic", r))
-				}
-				if job.CleanupFunc != nil {
-					job.CleanupFunc()
+	idle := time.NewTimer(p.idleTimeout)
+	defer idle.Stop()
+	for {
+		// load the broadcast channel before checking, so a Resize that lands
+		// after the check still wakes this worker
+		resized := *p.resized.Load()
+		if p.retire(p.maxWorkers.Load()) {
+			return
+		}
+		select {
+		case t, ok := <-p.work:
+			if !ok {
+				p.workers.Add(-1)
+				return
+			}
+			if <-p.handoff {
+				p.run(t)
+			}
+		case <-resized:
+		case <-idle.C:
+			if p.retire(p.minWorkers.Load()) {
+				return
+			}
+		}
+		idle.Reset(p.idleTimeout)
+	}
+}
+
+// run executes one dispatched job and publishes its outcome.
+func (p *Pool[T]) run(t *task[T]) {
+	if t.batch != nil {
+		p.runBatch(t.batch)
+		return
+	}
+	job := t.job
+	var res Result[T]
+	finished := false
+	ctx, endSpan := p.startSpan(t)
+	started := time.Now()
+	p.running(t, started)
+	p.activeWorkers.Add(1)
+	func() {
+		defer p.activeWorkers.Add(-1)
+		defer func() {
+			if r := recover(); r != nil {
+				if pe, ok := r.(*PanicError); ok && p.onPanic == PanicCrash {
+					panic(pe) // raised by recovered after logging
 				}
-			}()
-			p.processJob(job)
+				// a panic outside the job function, e.g. in a hook
+				pe := &PanicError{Value: r, Stack: debug.Stack()}
+				p.jobLog(ctx, t).log(LogPanic, "Job panicked", lg.Any("panic", r), lg.String("stack", string(pe.Stack)))
+				p.recovered(pe)
+				res = Result[T]{Status: StatusFailed, Err: pe, Attempts: t.attempts, Started: started}
+				finished = true
+			}
+			if job.CleanupFunc != nil {
+				job.CleanupFunc()
+			}
 		}()
+		res, finished = p.processJob(t, ctx)
+	}()
+	p.ran(ctx, t, res)
+	endSpan(res.Err)
+	p.releaseSerial(t)
+	p.running(t, time.Time{})
+	switch {
+	case t.sched != nil:
+		p.rearm(t)
+	case finished:
+		p.ack(t)
 	}
+	p.finish(t, res)
 }
 
-func (p *Pool[T]) processJob(job Job[T]) {
-	logger := lg.FromContext(job.Ctx).With(lg.Any("job", job.Payload))
-	logger.Info("Worker processing job", lg.Int32("active_workers", p.activeWorkers.Load()))
+// processJob runs the attempts of one job under ctx, which derives from
+// Job.Ctx. It reports false if the pool aborted the job before it finished,
+// so that it stays in the journal.
+func (p *Pool[T]) processJob(t *task[T], ctx context.Context) (res Result[T], finished bool) {
+	job := t.job
+	job.Ctx = ctx
+	logger := p.jobLog(ctx, t)
+	if p.runCtx.Err() != nil {
+		logger.log(LogCancel, "Job skipped: pool aborted")
+		return Result[T]{Status: StatusCanceled, Err: p.runCtx.Err()}, false
+	}
+	if err := job.Ctx.Err(); err != nil {
+		logger.log(LogCancel, "Job skipped: canceled while queued")
+		return Result[T]{Status: StatusCanceled, Err: err}, true
+	}
+	logger.log(LogStart, "Worker processing job", lg.Int32("active_workers", p.activeWorkers.Load()))
+	res.Started = time.Now()
+	t.handle.setStatus(StatusRunning)
+	p.started(ctx, t)
+
+	pol := p.defaultRetry.merge(job.Retry)
+	retry := newRetrier(pol)
 
-	pol := p.defaultRetry
-	if job.Retry != nil {
-		// override non-zero per-job values
-		if job.Retry.Attempts > 0 {
-			pol.Attempts = job.Retry.Attempts
+	first := 1
+	if job.remote != nil {
+		first = job.remote.attempt // earlier deliveries count too
+	}
+	var attempts []Attempt
+	for attempt := first; ; attempt++ {
+		res.Attempts = attempt
+		p.attempted(t, attempt)
+		start := time.Now()
+		err := p.runAttempt(job, pol.Timeout)
+		if err == nil {
+			logger.log(LogSuccess, "Worker finished", lg.Int("attempt", attempt))
+			res.Status, res.Err = StatusSucceeded, nil
+			return res, true
 		}
-		if job.Retry.Initial > 0 {
-			pol.Initial = job.Retry.Initial
+		res.Err = err
+		attempts = append(attempts, newAttempt(start, err))
+		pe, panicked := err.(*PanicError)
+		if panicked {
+			logger.log(LogPanic, "Job panicked", lg.Int("attempt", attempt), lg.Any("panic", pe.Value), lg.String("stack", string(pe.Stack)))
+			p.recovered(pe)
 		}
-		if job.Retry.Max > 0 {
-			pol.Max = job.Retry.Max
+		if p.runCtx.Err() != nil {
+			logger.log(LogCancel, "Job aborted by shutdown", lg.Int("attempt", attempt), lg.Error("error", err))
+			res.Status = StatusCanceled
+			return res, false
+		}
+		if job.Ctx.Err() != nil {
+			logger.log(LogCancel, "Job canceled", lg.Error("reason", job.Ctx.Err()))
+			res.Status = StatusCanceled
+			return res, true
+		}
+		if (panicked && p.onPanic == PanicFail) || !retry.retry(attempt, err) {
+			logger.log(LogFailure, "Job failed", lg.Int("attempt", attempt), lg.Error("error", err))
+			p.deadLetter(t, attempts, err)
+			res.Status = StatusFailed
+			return res, true
 		}
-	}
-
-	bo := boff.New(pol.Initial, pol.Max, time.Now().UnixNano())
 
-	for attempt := 1; attempt <= pol.Attempts; attempt++ {
-		if err := job.Fn(job.Payload); err == nil {
-			logger.Info("Worker finished", lg.Int32("active_workers", p.activeWorkers.Load()))
-			return
-		} else if attempt == pol.Attempts {
-			logger.Error("Worker error", lg.Int("attempt", attempt), lg.Any("error", err))
-			return
-		} else {
-			delay := bo.Next()
-			logger.Warn("job attempt failed; backing off",
+		delay := retry.delay()
+		if job.remote != nil {
+			// the backend redelivers it, possibly to another replica
+			for i := 1; i < attempt; i++ {
+				delay = retry.delay()
+			}
+			logger.log(LogRetry, "Job attempt failed; returned to the remote queue",
 				lg.Int("attempt", attempt),
 				lg.String("sleep", delay.String()),
-				lg.Any("error", err),
+				lg.Error("error", err),
 			)
-			timer := time.NewTimer(delay)
-			select {
-			case <-timer.C:
-			case <-job.Ctx.Done():
-				if !timer.Stop() {
-					<-timer.C // drain if timer is fired
-				}
-				logger.Info("Job canceled", lg.Any("reason", job.Ctx.Err()))
-				return
-			}
+			p.retrying(ctx, t, attempt, err, delay)
+			job.remote.delay = delay
+			res.Status = StatusRetrying
+			return res, true
+		}
+		logger.log(LogRetry, "Job attempt failed; backing off",
+			lg.Int("attempt", attempt),
+			lg.String("sleep", delay.String()),
+			lg.Error("error", err),
+		)
+		p.retrying(ctx, t, attempt, err, delay)
+		t.handle.setStatus(StatusRetrying)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+			t.handle.setStatus(StatusRunning)
+		case <-job.Ctx.Done():
+			timer.Stop()
+			logger.log(LogCancel, "Job canceled", lg.Error("reason", job.Ctx.Err()))
+			res.Status = StatusCanceled
+			return res, true
+		case <-p.runCtx.Done():
+			timer.Stop()
+			logger.log(LogCancel, "Job aborted by shutdown", lg.Int("attempt", attempt))
+			res.Status = StatusCanceled
+			return res, false
 		}
 	}
 }
 
+// runAttempt waits for the rate limiter, then calls the job function with a
+// context derived from Job.Ctx that is also canceled when the pool aborts or
+// the attempt times out.
+func (p *Pool[T]) runAttempt(job Job[T], timeout time.Duration) error {
+	ctx, cancel := context.WithCancel(job.Ctx)
+	defer cancel()
+	stop := context.AfterFunc(p.runCtx, cancel)
+	defer stop()
+	if err := p.limiter.wait(ctx); err != nil {
+		return err
+	}
+	if timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, timeout)
+		defer cancelTimeout()
+	}
+	return guard(func() error { return job.FnCtx(ctx, job.Payload) })
+}
+
 func (p *Pool[T]) ActiveWorkers() int32 { return p.activeWorkers.Load() }
-func (p *Pool[T]) QueueLength() int     { return len(p.jobs) }
+func (p *Pool[T]) QueueLength() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.queue.len()
+}